@@ -0,0 +1,211 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+// HubFieldsAnnotation keeps, in the v1alpha1 objects, the v1beta1 fields v1alpha1 can't represent,
+// so converting back to v1beta1 doesn't lose them.
+const HubFieldsAnnotation = "topologyexporter.openshift-kni.io/v1beta1-fields"
+
+// hubFields are the v1beta1 fields added after v1alpha1 was frozen.
+type hubFields struct {
+	UninstallPolicy  v1beta1.UninstallPolicy       `json:"uninstallPolicy,omitempty"`
+	ManagementState  v1beta1.ManagementState       `json:"managementState,omitempty"`
+	ExporterImage    string                        `json:"exporterImage,omitempty"`
+	ImagePullPolicy  corev1.PullPolicy             `json:"imagePullPolicy,omitempty"`
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	Status           hubStatusFields               `json:"status,omitempty"`
+}
+
+type hubStatusFields struct {
+	ObservedGeneration     int64                        `json:"observedGeneration,omitempty"`
+	DesiredNumberScheduled int32                        `json:"desiredNumberScheduled,omitempty"`
+	NumberReady            int32                        `json:"numberReady,omitempty"`
+	UpdatedNumberScheduled int32                        `json:"updatedNumberScheduled,omitempty"`
+	NodesWithStaleTopology int32                        `json:"nodesWithStaleTopology,omitempty"`
+	ExporterImage          *v1beta1.ImageStatus         `json:"exporterImage,omitempty"`
+	NodeTopology           []v1beta1.NodeTopologyStatus `json:"nodeTopology,omitempty"`
+	RelatedObjects         []v1beta1.ObjectReference    `json:"relatedObjects,omitempty"`
+}
+
+// ConvertTo converts this ResourceTopologyExporter to the Hub version (v1beta1).
+func (src *ResourceTopologyExporter) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.ResourceTopologyExporter)
+	dst.ObjectMeta = src.ObjectMeta
+	convertSpecTo(&src.Spec, &dst.Spec)
+	convertStatusTo(&src.Status, &dst.Status)
+
+	data, ok := src.Annotations[HubFieldsAnnotation]
+	if !ok {
+		return nil
+	}
+	dst.Annotations = withoutAnnotation(src.Annotations, HubFieldsAnnotation)
+	fields := hubFields{}
+	if err := json.Unmarshal([]byte(data), &fields); err != nil {
+		return err
+	}
+	restoreHubFields(&fields, dst)
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *ResourceTopologyExporter) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.ResourceTopologyExporter)
+	dst.ObjectMeta = src.ObjectMeta
+	convertSpecFrom(&src.Spec, &dst.Spec)
+	convertStatusFrom(&src.Status, &dst.Status)
+
+	fields := saveHubFields(src)
+	if equality.Semantic.DeepEqual(fields, hubFields{}) {
+		return nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	dst.Annotations = withoutAnnotation(src.Annotations, HubFieldsAnnotation)
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[HubFieldsAnnotation] = string(data)
+	return nil
+}
+
+func saveHubFields(src *v1beta1.ResourceTopologyExporter) hubFields {
+	return hubFields{
+		UninstallPolicy:  src.Spec.UninstallPolicy,
+		ManagementState:  src.Spec.ManagementState,
+		ExporterImage:    src.Spec.ExporterImage,
+		ImagePullPolicy:  src.Spec.ImagePullPolicy,
+		ImagePullSecrets: src.Spec.ImagePullSecrets,
+		Status: hubStatusFields{
+			ObservedGeneration:     src.Status.ObservedGeneration,
+			DesiredNumberScheduled: src.Status.DesiredNumberScheduled,
+			NumberReady:            src.Status.NumberReady,
+			UpdatedNumberScheduled: src.Status.UpdatedNumberScheduled,
+			NodesWithStaleTopology: src.Status.NodesWithStaleTopology,
+			ExporterImage:          src.Status.ExporterImage,
+			NodeTopology:           src.Status.NodeTopology,
+			RelatedObjects:         src.Status.RelatedObjects,
+		},
+	}
+}
+
+func restoreHubFields(fields *hubFields, dst *v1beta1.ResourceTopologyExporter) {
+	dst.Spec.UninstallPolicy = fields.UninstallPolicy
+	dst.Spec.ManagementState = fields.ManagementState
+	dst.Spec.ExporterImage = fields.ExporterImage
+	dst.Spec.ImagePullPolicy = fields.ImagePullPolicy
+	dst.Spec.ImagePullSecrets = fields.ImagePullSecrets
+	dst.Status.ObservedGeneration = fields.Status.ObservedGeneration
+	dst.Status.DesiredNumberScheduled = fields.Status.DesiredNumberScheduled
+	dst.Status.NumberReady = fields.Status.NumberReady
+	dst.Status.UpdatedNumberScheduled = fields.Status.UpdatedNumberScheduled
+	dst.Status.NodesWithStaleTopology = fields.Status.NodesWithStaleTopology
+	dst.Status.ExporterImage = fields.Status.ExporterImage
+	dst.Status.NodeTopology = fields.Status.NodeTopology
+	dst.Status.RelatedObjects = fields.Status.RelatedObjects
+}
+
+// withoutAnnotation returns a copy of the given annotations without the given one, or nil if none is left.
+func withoutAnnotation(annotations map[string]string, key string) map[string]string {
+	ret := make(map[string]string)
+	for k, v := range annotations {
+		if k != key {
+			ret[k] = v
+		}
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+	return ret
+}
+
+func convertSpecTo(src *ResourceTopologyExporterSpec, dst *v1beta1.ResourceTopologyExporterSpec) {
+	dst.NodeSelector = src.NodeSelector
+	dst.Tolerations = src.Tolerations
+	dst.Affinity = src.Affinity
+	dst.PollInterval = src.PollInterval
+	dst.TopologyManagerPolicy = v1beta1.TopologyManagerPolicy(src.TopologyManagerPolicy)
+	dst.KubeletStateDirs = src.KubeletStateDirs
+	dst.ReferenceContainer = src.ReferenceContainer
+	dst.Config = nil
+	if src.Config != nil {
+		dst.Config = &v1beta1.ExporterConfig{
+			ExcludeList: src.Config.ExcludeList,
+		}
+		if src.Config.Resources != nil {
+			dst.Config.Resources = &v1beta1.ResourcesConfig{
+				ReservedCPUs:    src.Config.Resources.ReservedCPUs,
+				ResourceMapping: src.Config.Resources.ResourceMapping,
+			}
+		}
+	}
+}
+
+func convertSpecFrom(src *v1beta1.ResourceTopologyExporterSpec, dst *ResourceTopologyExporterSpec) {
+	dst.NodeSelector = src.NodeSelector
+	dst.Tolerations = src.Tolerations
+	dst.Affinity = src.Affinity
+	dst.PollInterval = src.PollInterval
+	dst.TopologyManagerPolicy = TopologyManagerPolicy(src.TopologyManagerPolicy)
+	dst.KubeletStateDirs = src.KubeletStateDirs
+	dst.ReferenceContainer = src.ReferenceContainer
+	dst.Config = nil
+	if src.Config != nil {
+		dst.Config = &ExporterConfig{
+			ExcludeList: src.Config.ExcludeList,
+		}
+		if src.Config.Resources != nil {
+			dst.Config.Resources = &ResourcesConfig{
+				ReservedCPUs:    src.Config.Resources.ReservedCPUs,
+				ResourceMapping: src.Config.Resources.ResourceMapping,
+			}
+		}
+	}
+}
+
+func convertStatusTo(src *ResourceTopologyExporterStatus, dst *v1beta1.ResourceTopologyExporterStatus) {
+	dst.DaemonSet = nil
+	if src.DaemonSet != nil {
+		dst.DaemonSet = &v1beta1.NamespacedName{
+			Namespace: src.DaemonSet.Namespace,
+			Name:      src.DaemonSet.Name,
+		}
+	}
+	dst.Conditions = src.Conditions
+}
+
+func convertStatusFrom(src *v1beta1.ResourceTopologyExporterStatus, dst *ResourceTopologyExporterStatus) {
+	dst.DaemonSet = nil
+	if src.DaemonSet != nil {
+		dst.DaemonSet = &NamespacedName{
+			Namespace: src.DaemonSet.Namespace,
+			Name:      src.DaemonSet.Name,
+		}
+	}
+	dst.Conditions = src.Conditions
+}
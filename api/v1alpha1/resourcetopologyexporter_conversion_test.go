@@ -0,0 +1,126 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+const fuzzIterations = 100
+
+// newFuzzer returns a fuzzer filling the times with whole seconds, as serialized in the annotations.
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(0.2).NumElements(0, 3).Funcs(
+		func(t *metav1.Time, c fuzz.Continue) {
+			*t = metav1.Unix(c.Int63n(1<<32), 0)
+		},
+	)
+}
+
+func TestRoundTripFromSpoke(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		orig := &ResourceTopologyExporter{}
+		f.Fuzz(orig)
+		orig.TypeMeta = metav1.TypeMeta{}
+
+		hub := &v1beta1.ResourceTopologyExporter{}
+		if err := orig.DeepCopy().ConvertTo(hub); err != nil {
+			t.Fatalf("ConvertTo failed: %v", err)
+		}
+		got := &ResourceTopologyExporter{}
+		if err := got.ConvertFrom(hub); err != nil {
+			t.Fatalf("ConvertFrom failed: %v", err)
+		}
+
+		if !equality.Semantic.DeepEqual(orig, got) {
+			t.Fatalf("round trip mismatch:\noriginal  %#v\nconverted %#v", orig, got)
+		}
+	}
+}
+
+func TestRoundTripFromHub(t *testing.T) {
+	f := newFuzzer()
+	for i := 0; i < fuzzIterations; i++ {
+		orig := &v1beta1.ResourceTopologyExporter{}
+		f.Fuzz(orig)
+		orig.TypeMeta = metav1.TypeMeta{}
+
+		spoke := &ResourceTopologyExporter{}
+		if err := spoke.ConvertFrom(orig.DeepCopy()); err != nil {
+			t.Fatalf("ConvertFrom failed: %v", err)
+		}
+		got := &v1beta1.ResourceTopologyExporter{}
+		if err := spoke.ConvertTo(got); err != nil {
+			t.Fatalf("ConvertTo failed: %v", err)
+		}
+
+		if !equality.Semantic.DeepEqual(orig, got) {
+			t.Fatalf("round trip mismatch:\noriginal  %#v\nconverted %#v", orig, got)
+		}
+	}
+}
+
+func TestConvertFromKeepsHubFields(t *testing.T) {
+	hub := &v1beta1.ResourceTopologyExporter{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pool-a",
+			Annotations: map[string]string{"example.com/team": "numa"},
+		},
+		Spec: v1beta1.ResourceTopologyExporterSpec{
+			ExporterImage:   "quay.io/k8stopologyawareschedwg/resource-topology-exporter:v0.2.5",
+			ManagementState: v1beta1.ManagementStateUnmanaged,
+		},
+		Status: v1beta1.ResourceTopologyExporterStatus{
+			NumberReady: 3,
+		},
+	}
+
+	spoke := &ResourceTopologyExporter{}
+	if err := spoke.ConvertFrom(hub.DeepCopy()); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	if _, ok := spoke.Annotations[HubFieldsAnnotation]; !ok {
+		t.Fatalf("v1beta1 fields not kept in the annotations: %v", spoke.Annotations)
+	}
+	if _, ok := hub.Annotations[HubFieldsAnnotation]; ok {
+		t.Errorf("v1beta1 object annotations changed: %v", hub.Annotations)
+	}
+
+	got := &v1beta1.ResourceTopologyExporter{}
+	if err := spoke.ConvertTo(got); err != nil {
+		t.Fatalf("ConvertTo failed: %v", err)
+	}
+	if !equality.Semantic.DeepEqual(hub, got) {
+		t.Errorf("round trip mismatch:\noriginal  %#v\nconverted %#v", hub, got)
+	}
+
+	plain := &ResourceTopologyExporter{}
+	if err := plain.ConvertFrom(&v1beta1.ResourceTopologyExporter{}); err != nil {
+		t.Fatalf("ConvertFrom failed: %v", err)
+	}
+	if len(plain.Annotations) != 0 {
+		t.Errorf("unexpected annotations without v1beta1 fields: %v", plain.Annotations)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -41,8 +42,80 @@ func (n NamespacedName) String() string {
 	return n.Namespace + string(Separator) + n.Name
 }
 
+// TopologyManagerPolicy is the kubelet topology manager policy the exporter reports.
+// +kubebuilder:validation:Enum=none;best-effort;restricted;single-numa-node
+type TopologyManagerPolicy string
+
+const (
+	TopologyManagerPolicyNone           TopologyManagerPolicy = "none"
+	TopologyManagerPolicyBestEffort     TopologyManagerPolicy = "best-effort"
+	TopologyManagerPolicyRestricted     TopologyManagerPolicy = "restricted"
+	TopologyManagerPolicySingleNUMANode TopologyManagerPolicy = "single-numa-node"
+)
+
+// ResourcesConfig tunes the resources the exporter detects on the nodes.
+type ResourcesConfig struct {
+	// ReservedCPUs is the cpu list, in the cpuset format (e.g. "0-1,6"),
+	// of the cpus which should not be reported as allocatable.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*)?$`
+	// +optional
+	ReservedCPUs string `json:"reservedCPUs,omitempty"`
+
+	// ResourceMapping maps PCI devices, identified either by "vendor:device" or by "vendor"
+	// (4-digits hex values), to the resource name they should be reported as.
+	// +optional
+	ResourceMapping map[string]string `json:"resourceMapping,omitempty"`
+}
+
+// ExporterConfig is the exporter configuration, which the operator manages as ConfigMap.
+type ExporterConfig struct {
+	// ExcludeList maps node names, or "*" for all the nodes, to the resources
+	// the exporter should not report.
+	// +optional
+	ExcludeList map[string][]string `json:"excludeList,omitempty"`
+
+	// Resources tunes the resources the exporter detects on the nodes.
+	// +optional
+	Resources *ResourcesConfig `json:"resources,omitempty"`
+}
+
 // ResourceTopologyExporterSpec defines the desired state of ResourceTopologyExporter
 type ResourceTopologyExporterSpec struct {
+	// NodeSelector restricts the nodes on which the exporter pods are scheduled.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are applied to the exporter pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity is applied to the exporter pods.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// PollInterval is the time the exporter sleeps between podresources API polls.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// TopologyManagerPolicy explicitly sets the topology manager policy reported by the exporter,
+	// instead of learning it from the kubelet.
+	// +optional
+	TopologyManagerPolicy TopologyManagerPolicy `json:"topologyManagerPolicy,omitempty"`
+
+	// KubeletStateDirs are the kubelet state directories, as seen from the exporter container,
+	// the exporter watches for smart polling.
+	// +optional
+	KubeletStateDirs []string `json:"kubeletStateDirs,omitempty"`
+
+	// ReferenceContainer is the container used to learn about the shared cpu pool,
+	// in the "namespace/podname/containername" format.
+	// +optional
+	ReferenceContainer string `json:"referenceContainer,omitempty"`
+
+	// Config is the exporter configuration. If given, it is rendered into a ConfigMap
+	// mounted into the exporter pods.
+	// +optional
+	Config *ExporterConfig `json:"config,omitempty"`
 }
 
 // ResourceTopologyExporterStatus defines the observed state of ResourceTopologyExporter
@@ -0,0 +1,299 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// This is borrowed from the kubernetes source, because controller-gen
+// complains about the kube native type:
+// encountered struct field "Namespace" without JSON tag in type "NamespacedName"
+// at least until kube catches up, we just inline this simple struct here.
+
+// NamespacedName comprises a resource name, with a mandatory namespace,
+// rendered as "<namespace>/<name>".
+type NamespacedName struct {
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+}
+
+const (
+	Separator = '/'
+)
+
+// String returns the general purpose string representation
+func (n NamespacedName) String() string {
+	return n.Namespace + string(Separator) + n.Name
+}
+
+// TopologyManagerPolicy is the kubelet topology manager policy the exporter reports.
+// +kubebuilder:validation:Enum=none;best-effort;restricted;single-numa-node
+type TopologyManagerPolicy string
+
+const (
+	TopologyManagerPolicyNone           TopologyManagerPolicy = "none"
+	TopologyManagerPolicyBestEffort     TopologyManagerPolicy = "best-effort"
+	TopologyManagerPolicyRestricted     TopologyManagerPolicy = "restricted"
+	TopologyManagerPolicySingleNUMANode TopologyManagerPolicy = "single-numa-node"
+)
+
+// UninstallPolicy tells what the operator removes, besides the exporter, when the instance is deleted.
+// +kubebuilder:validation:Enum=Retain;DeleteNRTObjects;DeleteNRTObjectsAndCRD
+type UninstallPolicy string
+
+const (
+	// UninstallPolicyRetain leaves the NodeResourceTopology objects and CRD untouched.
+	UninstallPolicyRetain UninstallPolicy = "Retain"
+	// UninstallPolicyDeleteNRTObjects deletes the NodeResourceTopology objects of the nodes the instance served.
+	UninstallPolicyDeleteNRTObjects UninstallPolicy = "DeleteNRTObjects"
+	// UninstallPolicyDeleteNRTObjectsAndCRD also deletes the NodeResourceTopology CRD, unless other instances still need it.
+	UninstallPolicyDeleteNRTObjectsAndCRD UninstallPolicy = "DeleteNRTObjectsAndCRD"
+)
+
+// ManagementState tells how much the operator manages the exporter of an instance.
+// +kubebuilder:validation:Enum=Managed;Unmanaged;Removed
+type ManagementState string
+
+const (
+	// ManagementStateManaged lets the operator deploy the exporter and keep it as specified.
+	ManagementStateManaged ManagementState = "Managed"
+	// ManagementStateUnmanaged stops the operator from changing the exporter, which is still reported in the status.
+	ManagementStateUnmanaged ManagementState = "Unmanaged"
+	// ManagementStateRemoved makes the operator remove the exporter, while keeping the instance.
+	ManagementStateRemoved ManagementState = "Removed"
+)
+
+// ResourcesConfig tunes the resources the exporter detects on the nodes.
+type ResourcesConfig struct {
+	// ReservedCPUs is the cpu list, in the cpuset format (e.g. "0-1,6"),
+	// of the cpus which should not be reported as allocatable.
+	// +kubebuilder:validation:Pattern=`^([0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*)?$`
+	// +optional
+	ReservedCPUs string `json:"reservedCPUs,omitempty"`
+
+	// ResourceMapping maps PCI devices, identified either by "vendor:device" or by "vendor"
+	// (4-digits hex values), to the resource name they should be reported as.
+	// +optional
+	ResourceMapping map[string]string `json:"resourceMapping,omitempty"`
+}
+
+// ExporterConfig is the exporter configuration, which the operator manages as ConfigMap.
+type ExporterConfig struct {
+	// ExcludeList maps node names, or "*" for all the nodes, to the resources
+	// the exporter should not report.
+	// +optional
+	ExcludeList map[string][]string `json:"excludeList,omitempty"`
+
+	// Resources tunes the resources the exporter detects on the nodes.
+	// +optional
+	Resources *ResourcesConfig `json:"resources,omitempty"`
+}
+
+// ResourceTopologyExporterSpec defines the desired state of ResourceTopologyExporter
+type ResourceTopologyExporterSpec struct {
+	// NodeSelector restricts the nodes on which the exporter pods are scheduled.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are applied to the exporter pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity is applied to the exporter pods.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// PollInterval is the time the exporter sleeps between podresources API polls.
+	// +optional
+	PollInterval *metav1.Duration `json:"pollInterval,omitempty"`
+
+	// TopologyManagerPolicy explicitly sets the topology manager policy reported by the exporter,
+	// instead of learning it from the kubelet.
+	// +optional
+	TopologyManagerPolicy TopologyManagerPolicy `json:"topologyManagerPolicy,omitempty"`
+
+	// KubeletStateDirs are the kubelet state directories, as seen from the exporter container,
+	// the exporter watches for smart polling. They are mounted read-only from the host: the ones
+	// under /host- from the path without that prefix, like /host-var/lib/kubelet from /var/lib/kubelet,
+	// the others from the same path.
+	// +optional
+	KubeletStateDirs []string `json:"kubeletStateDirs,omitempty"`
+
+	// ReferenceContainer is the container used to learn about the shared cpu pool,
+	// in the "namespace/podname/containername" format.
+	// +optional
+	ReferenceContainer string `json:"referenceContainer,omitempty"`
+
+	// Config is the exporter configuration. If given, it is rendered into a ConfigMap
+	// mounted into the exporter pods.
+	// +optional
+	Config *ExporterConfig `json:"config,omitempty"`
+
+	// UninstallPolicy tells what the operator removes, besides the exporter, when the instance is deleted.
+	// Defaults to Retain.
+	// +optional
+	UninstallPolicy UninstallPolicy `json:"uninstallPolicy,omitempty"`
+
+	// ManagementState tells if the operator manages the exporter, leaves it alone or removes it.
+	// Defaults to Managed.
+	// +optional
+	ManagementState ManagementState `json:"managementState,omitempty"`
+
+	// ExporterImage is the exporter image to deploy, instead of the operator default.
+	// Images given by tag are resolved to a digest, which is recorded in the status and deployed
+	// until the image changes, so mirrored registries work and rollouts are reproducible.
+	// +optional
+	ExporterImage string `json:"exporterImage,omitempty"`
+
+	// ImagePullPolicy is the pull policy of the exporter image.
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// ImagePullSecrets are the secrets, in the instance namespace, used to pull the exporter image
+	// and to resolve its digest.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// ObjectReference identifies an object managed by the operator.
+type ObjectReference struct {
+	Group     string `json:"group"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// NodeTopologyStatus reports if the NodeResourceTopology object of a node is kept up to date.
+type NodeTopologyStatus struct {
+	NodeName string `json:"nodeName"`
+
+	// Fresh is true if the node has a NodeResourceTopology object and a ready exporter pod updating it.
+	Fresh bool `json:"fresh"`
+
+	// Reason explains why the NodeResourceTopology object of the node is not fresh.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// LastUpdateTime is the last time the NodeResourceTopology object of the node was changed.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// ImageSource tells where the exporter image comes from.
+type ImageSource string
+
+const (
+	// ImageSourceCustomResource is the image selected in the instance spec.
+	ImageSourceCustomResource ImageSource = "CustomResource"
+	// ImageSourceEnvironment is the image set in the operator environment, e.g. by OLM for the mirrored registries.
+	ImageSourceEnvironment ImageSource = "Environment"
+	// ImageSourceOperatorPod is the image of the operator pod, which bundles the exporter.
+	ImageSourceOperatorPod ImageSource = "OperatorPod"
+	// ImageSourceDefault is the image compiled in the operator.
+	ImageSourceDefault ImageSource = "Default"
+)
+
+// ImageStatus reports the exporter image deployed by the operator.
+type ImageStatus struct {
+	// Image is the image as requested, possibly by tag.
+	Image string `json:"image"`
+
+	// Resolved is the image set in the DaemonSet, by digest if the requested image was resolved.
+	// Empty while the requested image can't be resolved: it is then set in the DaemonSet as requested.
+	// +optional
+	Resolved string `json:"resolved,omitempty"`
+
+	// Source tells where the image comes from.
+	// +optional
+	Source ImageSource `json:"source,omitempty"`
+}
+
+// ResourceTopologyExporterStatus defines the observed state of ResourceTopologyExporter
+type ResourceTopologyExporterStatus struct {
+	// ObservedGeneration is the most recent generation observed by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	DaemonSet *NamespacedName `json:"daemonset,omitempty"`
+
+	// DesiredNumberScheduled is the number of nodes which should run the exporter pod.
+	DesiredNumberScheduled int32 `json:"desiredNumberScheduled"`
+
+	// NumberReady is the number of nodes running a ready exporter pod.
+	NumberReady int32 `json:"numberReady"`
+
+	// UpdatedNumberScheduled is the number of nodes running the updated exporter pod.
+	UpdatedNumberScheduled int32 `json:"updatedNumberScheduled"`
+
+	// NodesWithStaleTopology is the number of nodes whose NodeResourceTopology object is not fresh.
+	NodesWithStaleTopology int32 `json:"nodesWithStaleTopology"`
+
+	// ExporterImage is the exporter image deployed.
+	// +optional
+	ExporterImage *ImageStatus `json:"exporterImage,omitempty"`
+
+	// NodeTopology reports the NodeResourceTopology object freshness for each node running the exporter,
+	// or, once the exporter is removed, as last seen on the nodes which ran it.
+	// +optional
+	NodeTopology []NodeTopologyStatus `json:"nodeTopology,omitempty"`
+
+	// RelatedObjects are the objects managed by the operator on behalf of this instance.
+	// +optional
+	RelatedObjects []ObjectReference `json:"relatedObjects,omitempty"`
+
+	// Conditions show the current state of the ResourceTopologyExporter Operator
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:storageversion
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=rte,path=resourcetopologyexporters
+//+kubebuilder:printcolumn:name="Available",type="string",JSONPath=".status.conditions[?(@.type==\"Available\")].status"
+//+kubebuilder:printcolumn:name="Progressing",type="string",JSONPath=".status.conditions[?(@.type==\"Progressing\")].status"
+//+kubebuilder:printcolumn:name="Degraded",type="string",JSONPath=".status.conditions[?(@.type==\"Degraded\")].status"
+//+kubebuilder:printcolumn:name="DaemonSet",type="string",JSONPath=".status.daemonset.name"
+//+kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".status.desiredNumberScheduled"
+//+kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.numberReady"
+//+kubebuilder:printcolumn:name="Up-to-date",type="integer",JSONPath=".status.updatedNumberScheduled",priority=1
+//+kubebuilder:printcolumn:name="Stale-NRT",type="integer",JSONPath=".status.nodesWithStaleTopology",description="Nodes without a fresh NodeResourceTopology object"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// ResourceTopologyExporter is the Schema for the resourcetopologyexporters API
+type ResourceTopologyExporter struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ResourceTopologyExporterSpec   `json:"spec,omitempty"`
+	Status ResourceTopologyExporterStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ResourceTopologyExporterList contains a list of ResourceTopologyExporter
+type ResourceTopologyExporterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ResourceTopologyExporter `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ResourceTopologyExporter{}, &ResourceTopologyExporterList{})
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const (
+	DefaultPollInterval    = 10 * time.Second
+	DefaultKubeletStateDir = "/host-var/lib/kubelet"
+)
+
+// SetupWebhookWithManager registers the defaulting webhook of the ResourceTopologyExporter type, and the
+// conversion webhook serving all the other versions, with the manager. The validating webhook is set up by
+// the validation package.
+func (r *ResourceTopologyExporter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-topologyexporter-openshift-kni-io-v1beta1-resourcetopologyexporter,mutating=true,failurePolicy=fail,sideEffects=None,groups=topologyexporter.openshift-kni.io,resources=resourcetopologyexporters,verbs=create;update,versions=v1beta1,name=mresourcetopologyexporter.kb.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Defaulter = &ResourceTopologyExporter{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *ResourceTopologyExporter) Default() {
+	if r.Spec.PollInterval == nil {
+		r.Spec.PollInterval = &metav1.Duration{Duration: DefaultPollInterval}
+	}
+	if len(r.Spec.KubeletStateDirs) == 0 {
+		r.Spec.KubeletStateDirs = []string{DefaultKubeletStateDir}
+	}
+	if r.Spec.UninstallPolicy == "" {
+		r.Spec.UninstallPolicy = UninstallPolicyRetain
+	}
+	if r.Spec.ManagementState == "" {
+		r.Spec.ManagementState = ManagementStateManaged
+	}
+}
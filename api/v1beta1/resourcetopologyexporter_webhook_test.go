@@ -0,0 +1,47 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefault(t *testing.T) {
+	rte := &ResourceTopologyExporter{}
+	rte.Default()
+	if rte.Spec.PollInterval == nil || rte.Spec.PollInterval.Duration != DefaultPollInterval {
+		t.Errorf("unexpected default poll interval: %v", rte.Spec.PollInterval)
+	}
+	if len(rte.Spec.KubeletStateDirs) != 1 || rte.Spec.KubeletStateDirs[0] != DefaultKubeletStateDir {
+		t.Errorf("unexpected default kubelet state dirs: %v", rte.Spec.KubeletStateDirs)
+	}
+	if rte.Spec.UninstallPolicy != UninstallPolicyRetain {
+		t.Errorf("unexpected default uninstall policy: %v", rte.Spec.UninstallPolicy)
+	}
+	if rte.Spec.ManagementState != ManagementStateManaged {
+		t.Errorf("unexpected default management state: %v", rte.Spec.ManagementState)
+	}
+
+	rte.Spec.PollInterval = &metav1.Duration{Duration: time.Minute}
+	rte.Default()
+	if rte.Spec.PollInterval.Duration != time.Minute {
+		t.Errorf("user-supplied poll interval overridden: %v", rte.Spec.PollInterval)
+	}
+}
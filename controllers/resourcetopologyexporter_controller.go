@@ -24,31 +24,48 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
 
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	nrtv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
 
 	apimanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/api"
 	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
 
-	topologyexporterv1alpha1 "github.com/openshift-kni/rte-operator/api/v1alpha1"
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
 
-	"github.com/openshift-kni/rte-operator/pkg/apply"
+	"github.com/openshift-kni/rte-operator/pkg/images"
+	"github.com/openshift-kni/rte-operator/pkg/metrics"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
 	apistate "github.com/openshift-kni/rte-operator/pkg/objectstate/api"
 	"github.com/openshift-kni/rte-operator/pkg/objectstate/rte"
 	rtestate "github.com/openshift-kni/rte-operator/pkg/objectstate/rte"
+	"github.com/openshift-kni/rte-operator/pkg/rteconfig"
 	"github.com/openshift-kni/rte-operator/pkg/status"
+	"github.com/openshift-kni/rte-operator/pkg/validation"
 )
 
-const (
-	defaultResourceTopologyExporterCrName = "resourcetopologyexporter"
-)
+// nodeTopologyResyncPeriod is how often the freshness of the NodeResourceTopology objects is checked
+const nodeTopologyResyncPeriod = 1 * time.Minute
 
 // ResourceTopologyExporterReconciler reconciles a ResourceTopologyExporter object
 type ResourceTopologyExporterReconciler struct {
@@ -59,8 +76,15 @@ type ResourceTopologyExporterReconciler struct {
 	APIManifests apimanifests.Manifests
 	RTEManifests rtemanifests.Manifests
 	Helper       *deployer.Helper
-	Namespace    string
-	ImageSpec    string
+	// DefaultImage is the exporter image deployed for the instances not selecting one
+	DefaultImage images.Selection
+	// ImageResolver resolves the exporter images given by tag. If nil, the images are deployed as given.
+	ImageResolver images.Resolver
+	Recorder      record.EventRecorder
+	// DryRun makes the reconciler report the changes it would make instead of making them
+	DryRun bool
+
+	imageBackoff *flowcontrol.Backoff
 }
 
 // TODO: narrow down
@@ -69,14 +93,17 @@ type ResourceTopologyExporterReconciler struct {
 // TODO
 
 // Cluster Scoped
-//+kubebuilder:rbac:groups=topology.node.k8s.io,resources=noderesourcetopologies,verbs=get;list;create;update
+//+kubebuilder:rbac:groups=topology.node.k8s.io,resources=noderesourcetopologies,verbs=get;list;watch;create;update;delete
 //+kubebuilder:rbac:groups=config.openshift.io,resources=clusterversionss,verbs=list
 //+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups=topologyexporter.openshift-kni.io,resources=resourcetopologyexporters,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=topologyexporter.openshift-kni.io,resources=resourcetopologyexporters/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=topologyexporter.openshift-kni.io,resources=resourcetopologyexporters/finalizers,verbs=update
@@ -90,130 +117,352 @@ func (r *ResourceTopologyExporterReconciler) Reconcile(ctx context.Context, req
 	_ = context.Background()
 	logger := r.Log.WithValues("rte", req.NamespacedName)
 
-	instance := &topologyexporterv1alpha1.ResourceTopologyExporter{}
+	instance := &topologyexporterv1beta1.ResourceTopologyExporter{}
 	err := r.Get(context.TODO(), req.NamespacedName, instance)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			metrics.Forget(req.NamespacedName)
+			r.imageBackoff.GC()
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return ctrl.Result{}, err
 	}
 
-	if req.Name != defaultResourceTopologyExporterCrName {
-		err := fmt.Errorf("ResourceTopologyExporter resource name must be %q", defaultResourceTopologyExporterCrName)
-		logger.Error(err, "Incorrect ResourceTopologyExporter resource name", "name", req.Name)
-		if err := status.Update(context.TODO(), r.Client, instance, status.ConditionDegraded, "IncorrectResourceTopologyExporterResourceName", fmt.Sprintf("Incorrect ResourceTopologyExporter resource name: %s", req.Name)); err != nil {
+	if instance.DeletionTimestamp != nil {
+		return r.finalize(ctx, instance)
+	}
+	if !r.DryRun {
+		if err := r.updateFinalizer(ctx, instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	switch instance.Spec.ManagementState {
+	case topologyexporterv1beta1.ManagementStateUnmanaged:
+		return r.reconcileUnmanaged(ctx, instance)
+	case topologyexporterv1beta1.ManagementStateRemoved:
+		return r.reconcileRemoved(ctx, instance)
+	}
+
+	other, err := r.findOverlappingInstance(ctx, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if other != nil {
+		message := fmt.Sprintf("node selector overlaps with the one of ResourceTopologyExporter %s", client.ObjectKeyFromObject(other))
+		logger.Info("Overlapping ResourceTopologyExporter node selectors", "other", client.ObjectKeyFromObject(other))
+		if err := r.updateStatus(context.TODO(), instance, status.ConditionDegraded, status.ReasonOverlappingNodeSelector, message); err != nil {
 			logger.Error(err, "Failed to update resourcetopologyexporter status", "Desired status", status.ConditionDegraded)
 		}
-		return ctrl.Result{}, nil // Return success to avoid requeue
+		return ctrl.Result{}, nil // Return success to avoid requeue: we will be notified when the other instance changes
 	}
 
+	retryResolve := r.resolveExporterImage(ctx, instance)
+
 	// note we intentionally NOT update the APIManifests - it is expected to be a NOP anyway
-	if r.Namespace != req.NamespacedName.Namespace {
-		r.RTEManifests = r.RenderManifests(req.NamespacedName.Namespace)
-		r.Namespace = req.NamespacedName.Namespace
+	// the RTE manifests depend on the instance spec, so we need to render them on each iteration
+	rteManifests, err := r.RenderManifests(instance)
+	if err != nil {
+		logger.Error(err, "Invalid ResourceTopologyExporter configuration")
+		if err := r.updateStatus(context.TODO(), instance, status.ConditionDegraded, status.ReasonInvalidConfig, err.Error()); err != nil {
+			logger.Error(err, "Failed to update resourcetopologyexporter status", "Desired status", status.ConditionDegraded)
+		}
+		return ctrl.Result{}, nil // Return success to avoid requeue: the configuration must be fixed by the user
 	}
 
-	result, condition, err := r.reconcileResource(ctx, req, instance)
+	report := &reconcileReport{}
+	result, condition, err := r.reconcileResource(ctx, req, instance, rteManifests, report)
 	if condition != "" {
-		// TODO: use proper reason
-		reason, message := condition, messageFromError(err)
-		if err := status.Update(context.TODO(), r.Client, instance, condition, reason, message); err != nil {
+		reason, message := status.ReasonFromError(err)
+		switch {
+		case err != nil:
+		case report.hasChanges():
+			reason, message = status.ReasonDryRun, report.message()
+		case report.hasConflicts():
+			reason, message = status.ReasonApplyConflict, report.conflictMessage()
+		case condition == status.ConditionProgressing && isReverting(instance):
+			// keep reporting the reverted changes until they are rolled out
+			cond := status.FindCondition(instance.Status.Conditions, status.ConditionProgressing)
+			reason, message = status.Reason(cond.Reason), cond.Message
+		case condition == status.ConditionProgressing:
+			reason, message = status.ReasonDaemonSetNotReady, fmt.Sprintf("%d out of %d exporter pods are ready", instance.Status.NumberReady, instance.Status.DesiredNumberScheduled)
+		}
+		if err := r.updateStatus(context.TODO(), instance, condition, reason, message, r.upgradeBlockers(ctx)...); err != nil {
 			logger.Info("Failed to update resourcetopologyexporter status", "Desired condition", condition, "error", err)
 		}
 	}
-	return result, err
+	return requeueSooner(result, retryResolve), err
+}
+
+// requeueSooner returns the given result requeued after the given delay, unless it is requeued sooner already.
+func requeueSooner(result ctrl.Result, after time.Duration) ctrl.Result {
+	if after > 0 && (result.RequeueAfter == 0 || after < result.RequeueAfter) {
+		result.RequeueAfter = after
+	}
+	return result
+}
+
+// isReverting tells if the instance is progressing towards reverting the changes of other field managers.
+func isReverting(instance *topologyexporterv1beta1.ResourceTopologyExporter) bool {
+	cond := status.FindCondition(instance.Status.Conditions, status.ConditionProgressing)
+	return cond != nil && cond.Status == metav1.ConditionTrue && cond.Reason == string(status.ReasonApplyConflict)
 }
 
-// RenderManifests renders the reconciler manifests so they can be deployed on the cluster.
-func (r *ResourceTopologyExporterReconciler) RenderManifests(namespace string) rtemanifests.Manifests {
-	logger := r.Log.WithValues("rte", namespace)
+// upgradeBlockers returns the reasons, besides the exporter rollout, which make upgrading the operator unsafe.
+func (r *ResourceTopologyExporterReconciler) upgradeBlockers(ctx context.Context) []status.UpgradeBlocker {
+	crd := apiextensionsv1.CustomResourceDefinition{}
+	if err := r.Get(ctx, client.ObjectKey{Name: r.APIManifests.Crd.Name}, &crd); err != nil {
+		// nothing to check against: the next reconciliation will create the CRD
+		return nil
+	}
+	if blocker := status.NRTAPIBlocker(&crd, nrtv1alpha1.SchemeGroupVersion.Version); blocker != nil {
+		return []status.UpgradeBlocker{*blocker}
+	}
+	return nil
+}
+
+// RenderManifests renders the reconciler manifests for the given instance so they can be deployed on the cluster.
+func (r *ResourceTopologyExporterReconciler) RenderManifests(instance *topologyexporterv1beta1.ResourceTopologyExporter) (rtemanifests.Manifests, error) {
+	logger := r.Log.WithValues("rte", client.ObjectKeyFromObject(instance))
 	logger.Info("Updating manifests")
+	// the admission webhooks may be disabled, so we must not trust the spec
+	if err := validation.ValidateSpec(&instance.Spec, field.NewPath("spec")).ToAggregate(); err != nil {
+		return r.RTEManifests, err
+	}
+	configData, err := rteconfig.Render(instance.Spec.Config)
+	if err != nil {
+		return r.RTEManifests, err
+	}
 	mf := r.RTEManifests.Update(rtemanifests.UpdateOptions{
-		Namespace: namespace,
+		ConfigData: configData,
+		Namespace:  instance.Namespace,
 	})
-	rtestate.UpdateDaemonSetImage(mf.DaemonSet, r.ImageSpec)
-	return mf
+	mf = rtestate.UpdateNames(mf, instance.Name)
+	if rtestate.FindExporterContainer(mf.DaemonSet) == nil {
+		return r.RTEManifests, fmt.Errorf("no container %q in the exporter DaemonSet manifest", rtestate.ExporterContainerName)
+	}
+	rtestate.UpdateDaemonSetImage(mf.DaemonSet, r.exporterImage(instance))
+	rtestate.UpdateDaemonSetPullOptions(mf.DaemonSet, instance.Spec.ImagePullPolicy, instance.Spec.ImagePullSecrets)
+	rtestate.UpdateDaemonSetPlacement(mf.DaemonSet, instance.Spec.NodeSelector, instance.Spec.Tolerations, instance.Spec.Affinity)
+	rtestate.UpdateDaemonSetCommand(mf.DaemonSet, instance.Spec)
+	rtestate.UpdateDaemonSetConfigHash(mf.DaemonSet, mf.ConfigMap)
+	return mf, nil
 }
 
-func messageFromError(err error) string {
-	if err == nil {
-		return ""
+// findOverlappingInstance returns the oldest instance, if any, whose node selector may select
+// the same nodes of the given instance. Only the oldest instance is deployed on the shared nodes.
+func (r *ResourceTopologyExporterReconciler) findOverlappingInstance(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) (*topologyexporterv1beta1.ResourceTopologyExporter, error) {
+	instances := topologyexporterv1beta1.ResourceTopologyExporterList{}
+	if err := r.List(ctx, &instances); err != nil {
+		return nil, err
 	}
-	unwErr := errors.Unwrap(err)
-	if unwErr == nil {
-		return ""
+	for idx := range instances.Items {
+		other := &instances.Items[idx]
+		if other.UID == instance.UID || other.DeletionTimestamp != nil {
+			continue
+		}
+		// a removed instance runs no exporter, so it can't conflict with anyone
+		if other.Spec.ManagementState == topologyexporterv1beta1.ManagementStateRemoved {
+			continue
+		}
+		if !isOlderInstance(other, instance) {
+			continue
+		}
+		if nodeSelectorsOverlap(other.Spec.NodeSelector, instance.Spec.NodeSelector) {
+			return other, nil
+		}
 	}
-	return unwErr.Error()
+	return nil, nil
 }
 
-func (r *ResourceTopologyExporterReconciler) reconcileResource(ctx context.Context, req ctrl.Request, instance *topologyexporterv1alpha1.ResourceTopologyExporter) (ctrl.Result, string, error) {
+func isOlderInstance(a, b *topologyexporterv1beta1.ResourceTopologyExporter) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return client.ObjectKeyFromObject(a).String() < client.ObjectKeyFromObject(b).String()
+}
+
+// nodeSelectorsOverlap tells if the given node selectors may select the same nodes,
+// which happens unless they require different values for the same label.
+func nodeSelectorsOverlap(selA, selB map[string]string) bool {
+	for key, valA := range selA {
+		if valB, ok := selB[key]; ok && valA != valB {
+			return false
+		}
+	}
+	return true
+}
+
+// syncFailedReason returns the reason to report the failure to sync the given object with.
+func syncFailedReason(obj client.Object) status.Reason {
+	switch obj.(type) {
+	case *appsv1.DaemonSet:
+		return status.ReasonDaemonSetSyncFailed
+	case *corev1.ConfigMap:
+		return status.ReasonConfigSyncFailed
+	case *corev1.ServiceAccount, *rbacv1.Role, *rbacv1.RoleBinding:
+		return status.ReasonRBACSyncFailed
+	}
+	return status.ReasonReconcileFailed
+}
+
+func (r *ResourceTopologyExporterReconciler) reconcileResource(ctx context.Context, req ctrl.Request, instance *topologyexporterv1beta1.ResourceTopologyExporter, rteManifests rtemanifests.Manifests, report *reconcileReport) (ctrl.Result, string, error) {
 	var err error
-	err = r.syncNodeResourceTopologyAPI(instance)
+	err = r.syncNodeResourceTopologyAPI(instance, report)
 	if err != nil {
-		return ctrl.Result{}, status.ConditionDegraded, errors.Wrapf(err, "FailedAPISync")
+		return ctrl.Result{}, status.ConditionDegraded, err
 	}
 
-	dsInfo, err := r.syncResourceTopologyExporterResources(instance)
+	dsInfo, err := r.syncResourceTopologyExporterResources(instance, rteManifests, report)
 	if err != nil {
-		return ctrl.Result{}, status.ConditionDegraded, errors.Wrapf(err, "FailedRTESync")
+		return ctrl.Result{}, status.ConditionDegraded, err
 	}
-	ok, err := r.Helper.IsDaemonSetRunning(dsInfo.Namespace, dsInfo.Name)
+	instance.Status.DaemonSet = &dsInfo
+	instance.Status.RelatedObjects = status.RelatedObjects(append(r.APIManifests.ToObjects(), rteManifests.ToObjects()...))
+
+	if report.hasChanges() {
+		// the cluster is not going to converge by itself, but its state may change behind our back
+		return ctrl.Result{RequeueAfter: nodeTopologyResyncPeriod}, status.ConditionProgressing, nil
+	}
+
+	ok, err := r.updateExporterStatus(ctx, instance, dsInfo)
 	if err != nil {
-		return ctrl.Result{}, status.ConditionDegraded, err
+		return ctrl.Result{}, status.ConditionDegraded, status.WrapError(status.ReasonExporterStatusFailed, err)
 	}
-	if !ok {
+	if !ok || report.hasConflicts() {
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, status.ConditionProgressing, nil
 	}
 
-	instance.Status.DaemonSet = &dsInfo
-	return ctrl.Result{}, status.ConditionAvailable, nil
+	// the NodeResourceTopology objects are not owned by us, so we need to check them periodically
+	return ctrl.Result{RequeueAfter: nodeTopologyResyncPeriod}, status.ConditionAvailable, nil
+}
+
+// updateExporterStatus reports in the instance status the exporter pod counts and the freshness of the
+// NodeResourceTopology objects of the nodes running the exporter. Returns true if all the exporter pods are ready.
+func (r *ResourceTopologyExporterReconciler) updateExporterStatus(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter, dsInfo topologyexporterv1beta1.NamespacedName) (bool, error) {
+	ds := appsv1.DaemonSet{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: dsInfo.Namespace, Name: dsInfo.Name}, &ds); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	status.UpdateDaemonSetCounts(&instance.Status, &ds)
+
+	pods := corev1.PodList{}
+	if err := r.List(ctx, &pods, client.InNamespace(ds.Namespace), client.MatchingLabels(ds.Spec.Selector.MatchLabels)); err != nil {
+		return false, errors.Wrapf(err, "could not list the exporter pods")
+	}
+	// the exporter writes in its own namespace: other exporters may serve the same nodes elsewhere
+	nrts := nrtv1alpha1.NodeResourceTopologyList{}
+	if err := r.List(ctx, &nrts, client.InNamespace(ds.Namespace)); err != nil && !meta.IsNoMatchError(err) {
+		return false, errors.Wrapf(err, "could not list the noderesourcetopology objects")
+	}
+	instance.Status.NodeTopology, instance.Status.NodesWithStaleTopology = status.NodeTopology(pods.Items, nrts.Items)
+
+	ready := ds.Status.DesiredNumberScheduled > 0 && ds.Status.DesiredNumberScheduled == ds.Status.NumberReady
+	if isReverting(instance) {
+		// the changes of other field managers are reverted only once the pods run the template we applied
+		ready = ready && ds.Status.ObservedGeneration >= ds.Generation && ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled
+	}
+	return ready, nil
 }
 
-func (r *ResourceTopologyExporterReconciler) syncNodeResourceTopologyAPI(instance *topologyexporterv1alpha1.ResourceTopologyExporter) error {
+func (r *ResourceTopologyExporterReconciler) syncNodeResourceTopologyAPI(instance *topologyexporterv1beta1.ResourceTopologyExporter, report *reconcileReport) error {
 	logger := r.Log.WithName("APISync")
 	logger.Info("Start")
 
 	Existing := apistate.FromClient(context.TODO(), r.Client, r.Platform, r.APIManifests)
 
 	for _, objState := range Existing.State(r.APIManifests) {
-		if _, err := apply.ApplyObject(context.TODO(), logger, r.Client, objState); err != nil {
-			return errors.Wrapf(err, "could not create %s", objState.Desired.GetObjectKind().GroupVersionKind().String())
+		if _, err := r.applyObject(context.TODO(), logger, instance, objState, report); err != nil {
+			return status.WrapError(status.ReasonAPISyncFailed, errors.Wrapf(err, "could not create %s", objState.Desired.GetObjectKind().GroupVersionKind().String()))
 		}
 	}
 	return nil
 }
 
-func (r *ResourceTopologyExporterReconciler) syncResourceTopologyExporterResources(instance *topologyexporterv1alpha1.ResourceTopologyExporter) (topologyexporterv1alpha1.NamespacedName, error) {
+func (r *ResourceTopologyExporterReconciler) syncResourceTopologyExporterResources(instance *topologyexporterv1beta1.ResourceTopologyExporter, rteManifests rtemanifests.Manifests, report *reconcileReport) (topologyexporterv1beta1.NamespacedName, error) {
 	logger := r.Log.WithName("RTESync")
 	logger.Info("Start")
 
-	Existing := rtestate.FromClient(context.TODO(), r.Client, r.Platform, r.RTEManifests)
+	Existing := rtestate.FromClient(context.TODO(), r.Client, r.Platform, rteManifests)
 
-	res := topologyexporterv1alpha1.NamespacedName{}
-	for _, objState := range Existing.State(r.RTEManifests) {
-		if err := controllerutil.SetControllerReference(instance, objState.Desired, r.Scheme); err != nil {
-			return res, errors.Wrapf(err, "Failed to set controller reference to %s %s", objState.Desired.GetNamespace(), objState.Desired.GetName())
+	res := topologyexporterv1beta1.NamespacedName{}
+	for _, objState := range Existing.State(rteManifests) {
+		if err := r.setOwnership(instance, objState.Desired); err != nil {
+			return res, err
 		}
-		obj, err := apply.ApplyObject(context.TODO(), logger, r.Client, objState)
+		obj, err := r.applyObject(context.TODO(), logger, instance, objState, report)
 		if err != nil {
-			return res, errors.Wrapf(err, "could not apply (%s) %s/%s", objState.Desired.GetObjectKind().GroupVersionKind(), objState.Desired.GetNamespace(), objState.Desired.GetName())
+			return res, status.WrapError(syncFailedReason(objState.Desired), errors.Wrapf(err, "could not apply (%s) %s/%s", objState.Desired.GetObjectKind().GroupVersionKind(), objState.Desired.GetNamespace(), objState.Desired.GetName()))
 		}
 
 		if nname, ok := rte.NamespacedNameFromObject(obj); ok {
 			res = nname
 		}
 	}
+
+	if err := r.pruneResourceTopologyExporterResources(instance, rteManifests, report); err != nil {
+		return res, status.WrapError(status.ReasonPruneFailed, err)
+	}
 	return res, nil
 }
 
+// pruneResourceTopologyExporterResources deletes the objects previously created for the instance
+// which are no longer rendered, like the ConfigMap once the instance has no more configuration.
+func (r *ResourceTopologyExporterReconciler) pruneResourceTopologyExporterResources(instance *topologyexporterv1beta1.ResourceTopologyExporter, rteManifests rtemanifests.Manifests, report *reconcileReport) error {
+	logger := r.Log.WithName("RTEPrune")
+
+	prunable, err := objectstate.Prunable(context.TODO(), r.Client, instance.UID, rtestate.InventoryLists(), rteManifests.ToObjects())
+	if err != nil {
+		return errors.Wrapf(err, "could not list the objects to prune")
+	}
+	for _, obj := range prunable {
+		logger.Info("pruning", "object", fmt.Sprintf("%T %s", obj, client.ObjectKeyFromObject(obj)))
+		if err := r.deleteObject(context.TODO(), logger, instance, obj, report); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "could not prune %s", client.ObjectKeyFromObject(obj))
+		}
+	}
+	return nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ResourceTopologyExporterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.imageBackoff = newImageBackoff()
+	crdName := r.APIManifests.Crd.Name
+	isAPICRD := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetName() == crdName
+	})
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&topologyexporterv1alpha1.ResourceTopologyExporter{}).
+		For(&topologyexporterv1beta1.ResourceTopologyExporter{}).
+		// revert the changes made behind our back to the objects we manage
+		Owns(&appsv1.DaemonSet{}).
+		Owns(&rbacv1.Role{}).
+		Owns(&rbacv1.RoleBinding{}).
+		Owns(&corev1.ServiceAccount{}).
+		Owns(&corev1.ConfigMap{}).
+		// instances depend on each other for the node selectors overlap detection
+		Watches(&source.Kind{Type: &topologyexporterv1beta1.ResourceTopologyExporter{}}, handler.EnqueueRequestsFromMapFunc(r.allInstances)).
+		// the API CRD is shared among all the instances, so none of them can own it
+		Watches(&source.Kind{Type: &apiextensionsv1.CustomResourceDefinition{}}, handler.EnqueueRequestsFromMapFunc(r.allInstances), builder.WithPredicates(isAPICRD)).
 		Complete(r)
 }
+
+func (r *ResourceTopologyExporterReconciler) allInstances(obj client.Object) []reconcile.Request {
+	instances := topologyexporterv1beta1.ResourceTopologyExporterList{}
+	if err := r.List(context.TODO(), &instances); err != nil {
+		r.Log.Error(err, "Failed to list resourcetopologyexporters")
+		return nil
+	}
+	reqs := []reconcile.Request{}
+	for idx := range instances.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&instances.Items[idx])})
+	}
+	return reqs
+}
@@ -0,0 +1,407 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	nrtv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+	rtestate "github.com/openshift-kni/rte-operator/pkg/objectstate/rte"
+	"github.com/openshift-kni/rte-operator/pkg/status"
+)
+
+var _ = Describe("ResourceTopologyExporter controller", func() {
+	// much shorter than nodeTopologyResyncPeriod, so only the watches can explain the drift being reverted
+	const (
+		timeout   = 10 * time.Second
+		interval  = 250 * time.Millisecond
+		namespace = "default"
+	)
+
+	var instanceCount int
+	var instance *topologyexporterv1beta1.ResourceTopologyExporter
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		instanceCount++
+		instance = &topologyexporterv1beta1.ResourceTopologyExporter{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("drift-%d", instanceCount),
+				Namespace: namespace,
+			},
+			Spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				Config: &topologyexporterv1beta1.ExporterConfig{
+					Resources: &topologyexporterv1beta1.ResourcesConfig{
+						ReservedCPUs: "0",
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+		By("waiting for the DaemonSet to be created")
+		dsKey := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		ds := &appsv1.DaemonSet{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, dsKey, ds)
+		}, timeout, interval).Should(Succeed())
+
+		By("faking the exporter pods running, as there is no DaemonSet controller")
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, dsKey, ds); err != nil {
+				return err
+			}
+			ds.Status.DesiredNumberScheduled = 1
+			ds.Status.NumberReady = 1
+			ds.Status.UpdatedNumberScheduled = 1
+			return k8sClient.Status().Update(ctx, ds)
+		}, timeout, interval).Should(Succeed())
+
+		Eventually(func() bool {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return false
+			}
+			cond := status.FindCondition(updated.Status.Conditions, status.ConditionAvailable)
+			return cond != nil && cond.Status == metav1.ConditionTrue
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	AfterEach(func() {
+		// some tests delete the instance themselves
+		Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, instance))).To(Succeed())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), &topologyexporterv1beta1.ResourceTopologyExporter{})
+			return apierrors.IsNotFound(err)
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	It("should revert changes to the Role", func() {
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("rte", instance.Name)}
+		role := &rbacv1.Role{}
+		Expect(k8sClient.Get(ctx, key, role)).To(Succeed())
+		Expect(role.Rules).ToNot(BeEmpty())
+
+		role.Rules = nil
+		Expect(k8sClient.Update(ctx, role)).To(Succeed())
+
+		Eventually(func() []rbacv1.PolicyRule {
+			if err := k8sClient.Get(ctx, key, role); err != nil {
+				return nil
+			}
+			return role.Rules
+		}, timeout, interval).ShouldNot(BeEmpty())
+	})
+
+	It("should recreate the deleted ConfigMap", func() {
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("rte-config", instance.Name)}
+		cm := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, key, cm)).To(Succeed())
+		oldUID := cm.UID
+
+		Expect(k8sClient.Delete(ctx, cm)).To(Succeed())
+
+		Eventually(func() bool {
+			if err := k8sClient.Get(ctx, key, cm); err != nil {
+				return false
+			}
+			return cm.UID != oldUID && len(cm.Data) > 0
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	It("should prune the ConfigMap once the configuration is removed", func() {
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("rte-config", instance.Name)}
+		Expect(k8sClient.Get(ctx, key, &corev1.ConfigMap{})).To(Succeed())
+
+		Eventually(func() error {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return err
+			}
+			updated.Spec.Config = nil
+			return k8sClient.Update(ctx, updated)
+		}, timeout, interval).Should(Succeed())
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, key, &corev1.ConfigMap{})
+			return apierrors.IsNotFound(err)
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	It("should recreate the deleted DaemonSet", func() {
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		ds := &appsv1.DaemonSet{}
+		Expect(k8sClient.Get(ctx, key, ds)).To(Succeed())
+		oldUID := ds.UID
+
+		Expect(k8sClient.Delete(ctx, ds)).To(Succeed())
+
+		Eventually(func() bool {
+			if err := k8sClient.Get(ctx, key, ds); err != nil {
+				return false
+			}
+			return ds.UID != oldUID
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	It("should keep the DaemonSet labels added by others", func() {
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		ds := &appsv1.DaemonSet{}
+		Expect(k8sClient.Get(ctx, key, ds)).To(Succeed())
+
+		ds.Labels["example.com/team"] = "numa"
+		Expect(k8sClient.Update(ctx, ds, client.FieldOwner("admission-tool"))).To(Succeed())
+
+		Consistently(func() string {
+			if err := k8sClient.Get(ctx, key, ds); err != nil {
+				return ""
+			}
+			return ds.Labels["example.com/team"]
+		}, 2*time.Second, interval).Should(Equal("numa"))
+	})
+
+	It("should revert conflicting changes to the DaemonSet", func() {
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		ds := &appsv1.DaemonSet{}
+		Expect(k8sClient.Get(ctx, key, ds)).To(Succeed())
+
+		expectedImage := rtestate.FindExporterContainer(ds).Image
+		rtestate.FindExporterContainer(ds).Image = "quay.io/example/not-the-exporter:latest"
+		Expect(k8sClient.Update(ctx, ds, client.FieldOwner("someone-else"))).To(Succeed())
+
+		Eventually(func() string {
+			if err := k8sClient.Get(ctx, key, ds); err != nil {
+				return ""
+			}
+			return rtestate.FindExporterContainer(ds).Image
+		}, timeout, interval).Should(Equal(expectedImage))
+	})
+
+	It("should report conflicting changes to the DaemonSet", func() {
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		ds := &appsv1.DaemonSet{}
+		Expect(k8sClient.Get(ctx, key, ds)).To(Succeed())
+
+		rtestate.FindExporterContainer(ds).Image = "quay.io/example/not-the-exporter:latest"
+		Expect(k8sClient.Update(ctx, ds, client.FieldOwner("someone-else"))).To(Succeed())
+
+		Eventually(func() string {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return ""
+			}
+			cond := status.FindCondition(updated.Status.Conditions, status.ConditionProgressing)
+			if cond == nil || cond.Status != metav1.ConditionTrue {
+				return ""
+			}
+			return cond.Reason + ": " + cond.Message
+		}, timeout, interval).Should(And(
+			HavePrefix(string(status.ReasonApplyConflict)+": "),
+			ContainSubstring("someone-else"),
+		))
+	})
+
+	setManagementState := func(state topologyexporterv1beta1.ManagementState) {
+		Eventually(func() error {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return err
+			}
+			updated.Spec.ManagementState = state
+			return k8sClient.Update(ctx, updated)
+		}, timeout, interval).Should(Succeed())
+	}
+
+	It("should leave the exporter alone when unmanaged", func() {
+		setManagementState(topologyexporterv1beta1.ManagementStateUnmanaged)
+		Eventually(func() string {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return ""
+			}
+			cond := status.FindCondition(updated.Status.Conditions, status.ConditionAvailable)
+			if cond == nil || cond.Status != metav1.ConditionTrue {
+				return ""
+			}
+			return cond.Reason
+		}, timeout, interval).Should(Equal(string(status.ReasonUnmanaged)))
+
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("rte", instance.Name)}
+		role := &rbacv1.Role{}
+		Expect(k8sClient.Get(ctx, key, role)).To(Succeed())
+		role.Rules = nil
+		Expect(k8sClient.Update(ctx, role)).To(Succeed())
+
+		Consistently(func() []rbacv1.PolicyRule {
+			if err := k8sClient.Get(ctx, key, role); err != nil {
+				return []rbacv1.PolicyRule{{}}
+			}
+			return role.Rules
+		}, 2*time.Second, interval).Should(BeEmpty())
+	})
+
+	It("should remove the exporter and keep the instance when removed", func() {
+		dsKey := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		cmKey := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("rte-config", instance.Name)}
+
+		setManagementState(topologyexporterv1beta1.ManagementStateRemoved)
+		Eventually(func() bool {
+			dsErr := k8sClient.Get(ctx, dsKey, &appsv1.DaemonSet{})
+			cmErr := k8sClient.Get(ctx, cmKey, &corev1.ConfigMap{})
+			return apierrors.IsNotFound(dsErr) && apierrors.IsNotFound(cmErr)
+		}, timeout, interval).Should(BeTrue())
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), &topologyexporterv1beta1.ResourceTopologyExporter{})).To(Succeed())
+
+		setManagementState(topologyexporterv1beta1.ManagementStateManaged)
+		Eventually(func() error {
+			return k8sClient.Get(ctx, dsKey, &appsv1.DaemonSet{})
+		}, timeout, interval).Should(Succeed())
+	})
+
+	It("should delete the NodeResourceTopology objects of a removed instance once deleted", func() {
+		dsKey := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		ds := &appsv1.DaemonSet{}
+		Expect(k8sClient.Get(ctx, dsKey, ds)).To(Succeed())
+		nodeName := "node-" + instance.Name
+
+		By("faking an exporter pod which wrote the NodeResourceTopology of its node")
+		nrt := &nrtv1alpha1.NodeResourceTopology{
+			ObjectMeta:       metav1.ObjectMeta{Name: nodeName, Namespace: namespace},
+			TopologyPolicies: []string{"None"},
+			Zones:            nrtv1alpha1.ZoneList{},
+		}
+		Expect(k8sClient.Create(ctx, nrt)).To(Succeed())
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: namespace, Labels: ds.Spec.Selector.MatchLabels},
+			Spec: corev1.PodSpec{
+				NodeName:   nodeName,
+				Containers: []corev1.Container{{Name: rtestate.ExporterContainerName, Image: rtestate.FindExporterContainer(ds).Image}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+		defer func() {
+			Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, pod))).To(Succeed())
+		}()
+
+		Eventually(func() error {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return err
+			}
+			updated.Spec.UninstallPolicy = topologyexporterv1beta1.UninstallPolicyDeleteNRTObjects
+			return k8sClient.Update(ctx, updated)
+		}, timeout, interval).Should(Succeed())
+		Eventually(func() []topologyexporterv1beta1.NodeTopologyStatus {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return nil
+			}
+			return updated.Status.NodeTopology
+		}, timeout, interval).ShouldNot(BeEmpty())
+
+		setManagementState(topologyexporterv1beta1.ManagementStateRemoved)
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, dsKey, &appsv1.DaemonSet{}))
+		}, timeout, interval).Should(BeTrue())
+
+		Expect(k8sClient.Delete(ctx, instance)).To(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(nrt), &nrtv1alpha1.NodeResourceTopology{}))
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	It("should set the finalizer only when the uninstall policy needs a cleanup", func() {
+		hasFinalizer := func() bool {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return false
+			}
+			return controllerutil.ContainsFinalizer(updated, finalizerName)
+		}
+		setUninstallPolicy := func(policy topologyexporterv1beta1.UninstallPolicy) {
+			Eventually(func() error {
+				updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+					return err
+				}
+				updated.Spec.UninstallPolicy = policy
+				return k8sClient.Update(ctx, updated)
+			}, timeout, interval).Should(Succeed())
+		}
+
+		Consistently(hasFinalizer, 2*time.Second, interval).Should(BeFalse())
+
+		setUninstallPolicy(topologyexporterv1beta1.UninstallPolicyDeleteNRTObjects)
+		Eventually(hasFinalizer, timeout, interval).Should(BeTrue())
+
+		setUninstallPolicy(topologyexporterv1beta1.UninstallPolicyRetain)
+		Eventually(hasFinalizer, timeout, interval).Should(BeFalse())
+	})
+
+	It("should deploy the exporter image selected in the instance", func() {
+		const image = "quay.io/example/resource-topology-exporter@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		Eventually(func() error {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return err
+			}
+			updated.Spec.ExporterImage = image
+			updated.Spec.ImagePullPolicy = corev1.PullAlways
+			updated.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "mirror-registry"}}
+			return k8sClient.Update(ctx, updated)
+		}, timeout, interval).Should(Succeed())
+
+		key := client.ObjectKey{Namespace: namespace, Name: rtestate.ObjectName("resource-topology-exporter", instance.Name)}
+		Eventually(func() bool {
+			ds := &appsv1.DaemonSet{}
+			if err := k8sClient.Get(ctx, key, ds); err != nil {
+				return false
+			}
+			cnt := rtestate.FindExporterContainer(ds)
+			pullSecrets := ds.Spec.Template.Spec.ImagePullSecrets
+			return cnt != nil && cnt.Image == image && cnt.ImagePullPolicy == corev1.PullAlways &&
+				len(pullSecrets) == 1 && pullSecrets[0].Name == "mirror-registry"
+		}, timeout, interval).Should(BeTrue())
+
+		Eventually(func() *topologyexporterv1beta1.ImageStatus {
+			updated := &topologyexporterv1beta1.ResourceTopologyExporter{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(instance), updated); err != nil {
+				return nil
+			}
+			return updated.Status.ExporterImage
+		}, timeout, interval).Should(Equal(&topologyexporterv1beta1.ImageStatus{Image: image, Resolved: image, Source: topologyexporterv1beta1.ImageSourceCustomResource}))
+	})
+})
@@ -0,0 +1,124 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+
+	"github.com/openshift-kni/rte-operator/pkg/apply"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
+	"github.com/openshift-kni/rte-operator/pkg/status"
+)
+
+// maxEventMessageLen keeps the diffs reported through events well below the size the API server accepts
+const maxEventMessageLen = 1024
+
+// reconcileReport collects what a reconciliation must report besides its outcome: the objects which the
+// operator would change, were it not running in dry-run mode, and the changes of other field managers it reverted.
+type reconcileReport struct {
+	changes   []string
+	conflicts []string
+}
+
+func (rep *reconcileReport) add(verb string, obj client.Object) {
+	rep.changes = append(rep.changes, fmt.Sprintf("%s %s %s", verb, kindOf(obj), client.ObjectKeyFromObject(obj)))
+}
+
+func (rep *reconcileReport) hasChanges() bool {
+	return rep != nil && len(rep.changes) > 0
+}
+
+func (rep *reconcileReport) message() string {
+	return "dry-run: would " + strings.Join(rep.changes, ", ")
+}
+
+func (rep *reconcileReport) addConflict(objDesc string, managers []string) {
+	rep.conflicts = append(rep.conflicts, fmt.Sprintf("%s changed by %s", objDesc, strings.Join(managers, ", ")))
+}
+
+func (rep *reconcileReport) hasConflicts() bool {
+	return rep != nil && len(rep.conflicts) > 0
+}
+
+func (rep *reconcileReport) conflictMessage() string {
+	return "reverting the changes to " + strings.Join(rep.conflicts, "; ")
+}
+
+// applyObject applies the given object state, or, in dry-run mode, reports the changes it would make.
+func (r *ResourceTopologyExporterReconciler) applyObject(ctx context.Context, logger logr.Logger, instance *topologyexporterv1beta1.ResourceTopologyExporter, objState objectstate.ObjectState, report *reconcileReport) (client.Object, error) {
+	if !r.DryRun {
+		events := r.events(instance)
+		events.Conflict = report.addConflict
+		return apply.ApplyObject(ctx, logger, r.Client, events, objState)
+	}
+	if objState.Error != nil && !objState.IsNotFoundError() {
+		return nil, objState.Error
+	}
+
+	diff, err := apply.DiffObject(objState)
+	if err != nil {
+		return nil, err
+	}
+	if objState.IsNotFoundError() {
+		r.reportDryRun(logger, instance, "create", objState.Desired, diff, report)
+		return objState.Desired, nil
+	}
+	if diff != "" {
+		r.reportDryRun(logger, instance, "update", objState.Desired, diff, report)
+	}
+	return objState.Existing, nil
+}
+
+// deleteObject deletes the given object, or, in dry-run mode, reports it would delete it.
+func (r *ResourceTopologyExporterReconciler) deleteObject(ctx context.Context, logger logr.Logger, instance *topologyexporterv1beta1.ResourceTopologyExporter, obj client.Object, report *reconcileReport) error {
+	if !r.DryRun {
+		return r.deleteAndReport(ctx, instance, obj)
+	}
+	diff, err := apply.DeletionDiff(obj)
+	if err != nil {
+		return err
+	}
+	r.reportDryRun(logger, instance, "delete", obj, diff, report)
+	return nil
+}
+
+func (r *ResourceTopologyExporterReconciler) reportDryRun(logger logr.Logger, instance *topologyexporterv1beta1.ResourceTopologyExporter, verb string, obj client.Object, diff string, report *reconcileReport) {
+	report.add(verb, obj)
+	logger.Info("dry-run: would "+verb, "object", fmt.Sprintf("%s %s", kindOf(obj), client.ObjectKeyFromObject(obj)), "diff", diff)
+	message := diff
+	if len(message) > maxEventMessageLen {
+		message = message[:maxEventMessageLen] + "\n[truncated]"
+	}
+	r.events(instance).Normalf(string(status.ReasonDryRun), "would %s %s %s:\n%s", verb, kindOf(obj), client.ObjectKeyFromObject(obj), message)
+}
+
+// kindOf returns the kind of the given object, which may lack its GVK, like the items of typed lists.
+func kindOf(obj client.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; kind != "" {
+		return kind
+	}
+	return reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+}
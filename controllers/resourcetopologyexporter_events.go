@@ -0,0 +1,96 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+
+	"github.com/openshift-kni/rte-operator/pkg/apply"
+	"github.com/openshift-kni/rte-operator/pkg/metrics"
+	"github.com/openshift-kni/rte-operator/pkg/status"
+)
+
+const (
+	eventReasonDeleted      = "Deleted"
+	eventReasonDeleteFailed = "DeleteFailed"
+)
+
+// events returns where to report the changes made on behalf of the given instance.
+func (r *ResourceTopologyExporterReconciler) events(instance *topologyexporterv1beta1.ResourceTopologyExporter) apply.Events {
+	return apply.Events{Recorder: r.Recorder, Object: instance}
+}
+
+// updateStatus updates the status of the instance like status.Update does, then reports the conditions which changed.
+func (r *ResourceTopologyExporterReconciler) updateStatus(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter, condition string, reason status.Reason, message string, blockers ...status.UpgradeBlocker) error {
+	oldConditions := make([]metav1.Condition, len(instance.Status.Conditions))
+	copy(oldConditions, instance.Status.Conditions)
+
+	metrics.ReconcileResult(condition, string(reason))
+	err := status.Update(ctx, r.Client, instance, condition, reason, message, blockers...)
+	key := client.ObjectKeyFromObject(instance)
+	metrics.SetConditions(key, instance.Status.Conditions)
+	metrics.SetNodeTopology(key, instance.Status.NodeTopology)
+	if err != nil {
+		return err
+	}
+
+	events := r.events(instance)
+	for _, cond := range status.ChangedConditions(oldConditions, instance.Status.Conditions) {
+		if isBadCondition(cond) {
+			events.Warningf(cond.Type, "condition %s is now %s (%s): %s", cond.Type, cond.Status, cond.Reason, cond.Message)
+		} else {
+			events.Normalf(cond.Type, "condition %s is now %s (%s): %s", cond.Type, cond.Status, cond.Reason, cond.Message)
+		}
+	}
+	return nil
+}
+
+// isBadCondition tells if the given condition reports something needing attention.
+func isBadCondition(cond metav1.Condition) bool {
+	switch cond.Type {
+	case status.ConditionDegraded:
+		return cond.Status == metav1.ConditionTrue
+	case status.ConditionAvailable, status.ConditionUpgradeable, status.ConditionImageResolved:
+		return cond.Status == metav1.ConditionFalse
+	}
+	return false
+}
+
+// deleteAndReport deletes the given object on behalf of the instance, and reports it.
+func (r *ResourceTopologyExporterReconciler) deleteAndReport(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter, obj client.Object, opts ...client.DeleteOption) error {
+	err := r.Delete(ctx, obj, opts...)
+	if apierrors.IsNotFound(err) {
+		return err
+	}
+	if err != nil {
+		r.events(instance).Warningf(eventReasonDeleteFailed, "could not delete %s %s: %v", kindOf(obj), client.ObjectKeyFromObject(obj), err)
+		return err
+	}
+	if gvk, err := apiutil.GVKForObject(obj, r.Scheme); err == nil {
+		metrics.ObjectWrite(gvk, metrics.VerbDelete)
+	}
+	r.events(instance).Normalf(eventReasonDeleted, "deleted %s %s", kindOf(obj), client.ObjectKeyFromObject(obj))
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/flowcontrol"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+
+	"github.com/openshift-kni/rte-operator/pkg/images"
+	"github.com/openshift-kni/rte-operator/pkg/status"
+)
+
+// the registry may take ResolveTimeout to fail, so the failed resolutions are retried sparingly
+const (
+	imageResolveInitialBackoff = 30 * time.Second
+	imageResolveMaxBackoff     = 15 * time.Minute
+)
+
+func newImageBackoff() *flowcontrol.Backoff {
+	return flowcontrol.NewBackOff(imageResolveInitialBackoff, imageResolveMaxBackoff)
+}
+
+// resolveExporterImage records in the status the exporter image to deploy and where it comes from.
+// The images selected in the instance are resolved to a digest if given by tag, and the resolved image
+// is kept until the selection changes, so the exporter pods don't roll out, possibly to a different build,
+// every time the tag moves. The other images are set by the operator deployment and are used as given.
+// The registry may be reachable only through mirrors, or trusted only by the nodes, so the images which
+// can't be resolved are deployed by tag, reporting the failure in the ImageResolved condition.
+// A failed resolution is retried with an exponential backoff for as long as the instance generation doesn't
+// change: returns when to try again, or zero if there is nothing to retry.
+func (r *ResourceTopologyExporterReconciler) resolveExporterImage(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) time.Duration {
+	backoffID := string(instance.UID)
+	selection := images.Select(instance.Spec.ExporterImage, r.DefaultImage)
+	if selection.Source != images.SourceCustomResource || r.ImageResolver == nil {
+		instance.Status.ExporterImage = imageStatus(selection, selection.Image)
+		meta.RemoveStatusCondition(&instance.Status.Conditions, status.ConditionImageResolved)
+		r.imageBackoff.Reset(backoffID)
+		return 0
+	}
+	if isResolved(instance.Status.ExporterImage, selection) {
+		r.imageBackoff.Reset(backoffID)
+		return 0
+	}
+	now := r.imageBackoff.Clock.Now()
+	if !failedToResolve(instance, selection) {
+		// the spec changed since the last failure, if any, which may fix it
+		r.imageBackoff.Reset(backoffID)
+	} else if r.imageBackoff.IsInBackOffSinceUpdate(backoffID, now) {
+		return r.imageBackoff.Get(backoffID)
+	}
+
+	resolved, err := r.ImageResolver.Resolve(ctx, selection.Image, instance.Namespace, instance.Spec.ImagePullSecrets)
+	status.SetImageResolved(&instance.Status, instance.Generation, err)
+	if err != nil {
+		r.imageBackoff.Next(backoffID, now)
+		retryAfter := r.imageBackoff.Get(backoffID)
+		r.Log.Info("Cannot resolve the exporter image, deploying it as requested", "rte", instance.Name, "image", selection.Image, "error", err.Error(), "retryAfter", retryAfter)
+		instance.Status.ExporterImage = imageStatus(selection, "")
+		return retryAfter
+	}
+	r.imageBackoff.Reset(backoffID)
+	r.Log.Info("Resolved the exporter image", "rte", instance.Name, "image", selection.Image, "resolved", resolved)
+	instance.Status.ExporterImage = imageStatus(selection, resolved)
+	return 0
+}
+
+// failedToResolve tells if the status of the instance reports the failure to resolve the given selection
+// at the current generation of the instance.
+func failedToResolve(instance *topologyexporterv1beta1.ResourceTopologyExporter, selection images.Selection) bool {
+	st := instance.Status.ExporterImage
+	if st == nil || st.Image != selection.Image || st.Source != topologyexporterv1beta1.ImageSource(selection.Source) {
+		return false
+	}
+	cond := status.FindCondition(instance.Status.Conditions, status.ConditionImageResolved)
+	return cond != nil && cond.Status == metav1.ConditionFalse && cond.ObservedGeneration == instance.Generation
+}
+
+// exporterImage returns the exporter image to set in the DaemonSet of the given instance.
+func (r *ResourceTopologyExporterReconciler) exporterImage(instance *topologyexporterv1beta1.ResourceTopologyExporter) string {
+	selection := images.Select(instance.Spec.ExporterImage, r.DefaultImage)
+	if isResolved(instance.Status.ExporterImage, selection) {
+		return instance.Status.ExporterImage.Resolved
+	}
+	return selection.Image
+}
+
+// isResolved tells if the given image status reports the resolution of the given selection.
+func isResolved(st *topologyexporterv1beta1.ImageStatus, selection images.Selection) bool {
+	return st != nil && st.Image == selection.Image && st.Source == topologyexporterv1beta1.ImageSource(selection.Source) && st.Resolved != ""
+}
+
+func imageStatus(selection images.Selection, resolved string) *topologyexporterv1beta1.ImageStatus {
+	return &topologyexporterv1beta1.ImageStatus{
+		Image:    selection.Image,
+		Resolved: resolved,
+		Source:   topologyexporterv1beta1.ImageSource(selection.Source),
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
+	rtestate "github.com/openshift-kni/rte-operator/pkg/objectstate/rte"
+	"github.com/openshift-kni/rte-operator/pkg/status"
+)
+
+// reconcileUnmanaged only reports the state of the exporter, which the operator must not change,
+// so it can be patched by hand, e.g. while debugging.
+func (r *ResourceTopologyExporterReconciler) reconcileUnmanaged(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) (ctrl.Result, error) {
+	logger := r.Log.WithValues("rte", client.ObjectKeyFromObject(instance))
+	logger.Info("Unmanaged instance, reporting the status only")
+
+	condition, message := status.ConditionAvailable, "the exporter is not managed by the operator"
+	if instance.Status.DaemonSet != nil {
+		ok, err := r.updateExporterStatus(ctx, instance, *instance.Status.DaemonSet)
+		if err != nil {
+			condition, message = status.ConditionDegraded, err.Error()
+		} else if !ok {
+			condition, message = status.ConditionDegraded, "the exporter is not managed by the operator and its pods are not all ready"
+		}
+	}
+	if err := r.updateStatus(ctx, instance, condition, status.ReasonUnmanaged, message, r.upgradeBlockers(ctx)...); err != nil {
+		logger.Info("Failed to update resourcetopologyexporter status", "Desired condition", condition, "error", err)
+	}
+	// the NodeResourceTopology objects are not owned by us, so we need to check them periodically
+	return ctrl.Result{RequeueAfter: nodeTopologyResyncPeriod}, nil
+}
+
+// reconcileRemoved deletes the exporter and the objects supporting it, but keeps the instance around.
+// The NodeResourceTopology objects and CRD are handled by the uninstall policy once the instance is deleted.
+func (r *ResourceTopologyExporterReconciler) reconcileRemoved(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) (ctrl.Result, error) {
+	logger := r.Log.WithValues("rte", client.ObjectKeyFromObject(instance))
+	logger.Info("Removed instance, deleting the exporter")
+
+	report := &reconcileReport{}
+	if err := r.removeResourceTopologyExporterResources(ctx, instance, report); err != nil {
+		logger.Error(err, "Failed to remove the exporter")
+		if err := r.updateStatus(ctx, instance, status.ConditionDegraded, status.ReasonRemoveFailed, err.Error()); err != nil {
+			logger.Info("Failed to update resourcetopologyexporter status", "Desired condition", status.ConditionDegraded, "error", err)
+		}
+		return ctrl.Result{}, err
+	}
+
+	condition, reason, message := status.ConditionAvailable, status.ReasonRemoved, "the exporter was removed as requested"
+	if report.hasChanges() {
+		condition, reason, message = status.ConditionProgressing, status.ReasonDryRun, report.message()
+	} else {
+		instance.Status.DaemonSet = nil
+		instance.Status.DesiredNumberScheduled = 0
+		instance.Status.NumberReady = 0
+		instance.Status.UpdatedNumberScheduled = 0
+		// the NodeTopology is kept: it tells which objects the uninstall policy deletes along with the instance
+		instance.Status.RelatedObjects = nil
+		instance.Status.ExporterImage = nil
+	}
+	if err := r.updateStatus(ctx, instance, condition, reason, message); err != nil {
+		logger.Info("Failed to update resourcetopologyexporter status", "Desired condition", condition, "error", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// removeResourceTopologyExporterResources deletes all the objects in the inventory of the instance.
+func (r *ResourceTopologyExporterReconciler) removeResourceTopologyExporterResources(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter, report *reconcileReport) error {
+	logger := r.Log.WithName("RTERemove")
+
+	objs, err := objectstate.Prunable(ctx, r.Client, instance.UID, rtestate.InventoryLists(), nil)
+	if err != nil {
+		return errors.Wrapf(err, "could not list the objects to remove")
+	}
+	for _, obj := range objs {
+		logger.Info("removing", "object", fmt.Sprintf("%T %s", obj, client.ObjectKeyFromObject(obj)))
+		if err := r.deleteObject(ctx, logger, instance, obj, report); err != nil && !apierrors.IsNotFound(err) {
+			return errors.Wrapf(err, "could not remove %s", client.ObjectKeyFromObject(obj))
+		}
+	}
+	return nil
+}
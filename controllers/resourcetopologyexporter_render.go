@@ -0,0 +1,64 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/pkg/errors"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
+	apistate "github.com/openshift-kni/rte-operator/pkg/objectstate/api"
+	rtestate "github.com/openshift-kni/rte-operator/pkg/objectstate/rte"
+)
+
+// RenderObjects returns the objects the reconciler applies for the given instance, in the same order:
+// the NodeResourceTopology API first, then the exporter. The exporter objects are owned by the instance
+// only if it was read from the cluster, because an instance rendered offline has no UID to refer to.
+func (r *ResourceTopologyExporterReconciler) RenderObjects(instance *topologyexporterv1beta1.ResourceTopologyExporter) ([]client.Object, error) {
+	rteManifests, err := r.RenderManifests(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := []client.Object{}
+	for _, objState := range (apistate.ExistingManifests{}).State(r.APIManifests) {
+		objs = append(objs, objState.Desired)
+	}
+	for _, objState := range (rtestate.ExistingManifests{}).State(rteManifests) {
+		if instance.UID != "" {
+			if err := r.setOwnership(instance, objState.Desired); err != nil {
+				return nil, err
+			}
+		}
+		objs = append(objs, objState.Desired)
+	}
+	return objs, nil
+}
+
+// setOwnership makes the instance own the given exporter object, so the object is garbage collected
+// along with the instance, and pruned once the instance no longer needs it.
+func (r *ResourceTopologyExporterReconciler) setOwnership(instance *topologyexporterv1beta1.ResourceTopologyExporter, obj client.Object) error {
+	if err := controllerutil.SetControllerReference(instance, obj, r.Scheme); err != nil {
+		return errors.Wrapf(err, "Failed to set controller reference to %s %s", obj.GetNamespace(), obj.GetName())
+	}
+	objectstate.SetInventoryLabel(obj, instance.UID)
+	return nil
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	nrtv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+
+	"github.com/openshift-kni/rte-operator/pkg/metrics"
+	"github.com/openshift-kni/rte-operator/pkg/status"
+)
+
+// finalizerName guards the cleanup, according to the uninstall policy, of the objects not garbage collected
+// through the owner references: the NodeResourceTopology objects and CRD.
+const finalizerName = "topologyexporter.openshift-kni.io/finalizer"
+
+// updateFinalizer makes sure the instance can't go away before the operator cleaned up after it, as long as
+// its uninstall policy requires a cleanup. Otherwise the deletion of the instance needs not wait for the operator.
+func (r *ResourceTopologyExporterReconciler) updateFinalizer(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) error {
+	needed := needsCleanup(instance.Spec.UninstallPolicy)
+	if needed == controllerutil.ContainsFinalizer(instance, finalizerName) {
+		return nil
+	}
+	if needed {
+		controllerutil.AddFinalizer(instance, finalizerName)
+	} else {
+		controllerutil.RemoveFinalizer(instance, finalizerName)
+	}
+	return r.Update(ctx, instance)
+}
+
+// needsCleanup tells if the given uninstall policy removes anything once the instance is deleted.
+func needsCleanup(policy topologyexporterv1beta1.UninstallPolicy) bool {
+	return policy != "" && policy != topologyexporterv1beta1.UninstallPolicyRetain
+}
+
+// finalize runs the uninstall policy of a deleted instance, then lets it go away.
+func (r *ResourceTopologyExporterReconciler) finalize(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(instance, finalizerName) {
+		return ctrl.Result{}, nil
+	}
+	logger := r.Log.WithValues("rte", client.ObjectKeyFromObject(instance))
+	if r.DryRun {
+		// the finalizer was set by an operator not running in dry-run mode, which is the one expected to remove it
+		logger.Info("dry-run: would uninstall", "policy", instance.Spec.UninstallPolicy)
+		return ctrl.Result{}, nil
+	}
+
+	done, err := r.uninstall(ctx, instance)
+	if err != nil {
+		logger.Error(err, "Failed to uninstall", "policy", instance.Spec.UninstallPolicy)
+		if err := r.updateStatus(ctx, instance, status.ConditionDegraded, status.ReasonUninstallFailed, err.Error()); err != nil {
+			logger.Info("Failed to update resourcetopologyexporter status", "Desired condition", status.ConditionDegraded, "error", err)
+		}
+		return ctrl.Result{}, err
+	}
+	if !done {
+		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	}
+
+	logger.Info("Uninstall completed", "policy", instance.Spec.UninstallPolicy)
+	controllerutil.RemoveFinalizer(instance, finalizerName)
+	return ctrl.Result{}, r.Update(ctx, instance)
+}
+
+// uninstall removes the objects selected by the uninstall policy of the instance.
+// Returns false if the caller should check again later because the cleanup is still in progress.
+func (r *ResourceTopologyExporterReconciler) uninstall(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) (bool, error) {
+	policy := instance.Spec.UninstallPolicy
+	if !needsCleanup(policy) {
+		return true, nil
+	}
+
+	// the exporter pods would recreate the NodeResourceTopology objects, so they must go away first
+	if instance.Status.DaemonSet != nil {
+		gone, err := r.deleteDaemonSet(ctx, instance, *instance.Status.DaemonSet)
+		if err != nil {
+			return false, err
+		}
+		if !gone {
+			return false, r.updateUninstallProgress(ctx, instance, "waiting for the exporter pods to terminate")
+		}
+	}
+
+	others, err := r.otherInstances(ctx, instance)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.updateUninstallProgress(ctx, instance, "deleting the noderesourcetopology objects"); err != nil {
+		return false, err
+	}
+	if err := r.deleteNodeResourceTopologies(ctx, instance, others); err != nil {
+		return false, err
+	}
+
+	if policy != topologyexporterv1beta1.UninstallPolicyDeleteNRTObjectsAndCRD {
+		return true, nil
+	}
+	if len(others) > 0 {
+		r.Log.Info("Not deleting the noderesourcetopology CRD, still in use", "instances", len(others))
+		return true, nil
+	}
+	if err := r.updateUninstallProgress(ctx, instance, "deleting the noderesourcetopology CRD"); err != nil {
+		return false, err
+	}
+	crd := r.APIManifests.Crd.DeepCopy()
+	if err := r.deleteAndReport(ctx, instance, crd); err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "could not delete the CRD %s", crd.Name)
+	}
+	return true, nil
+}
+
+// deleteDaemonSet deletes the given DaemonSet once its pods are gone. Returns true if the DaemonSet no longer exists.
+func (r *ResourceTopologyExporterReconciler) deleteDaemonSet(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter, dsInfo topologyexporterv1beta1.NamespacedName) (bool, error) {
+	ds := appsv1.DaemonSet{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: dsInfo.Namespace, Name: dsInfo.Name}, &ds); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	if ds.DeletionTimestamp != nil {
+		return false, nil
+	}
+	// foreground deletion keeps the DaemonSet around until its pods are gone
+	err := r.deleteAndReport(ctx, instance, &ds, client.PropagationPolicy(metav1.DeletePropagationForeground))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, errors.Wrapf(err, "could not delete the daemonset %s", dsInfo.String())
+	}
+	return false, nil
+}
+
+// deleteNodeResourceTopologies deletes the NodeResourceTopology objects the exporter of the instance wrote,
+// which are the ones in the exporter namespace named after the nodes the instance served. The objects of
+// the nodes served by the given instances in the same namespace are kept, as their exporters share them.
+func (r *ResourceTopologyExporterReconciler) deleteNodeResourceTopologies(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter, others []topologyexporterv1beta1.ResourceTopologyExporter) error {
+	namespace := exporterNamespace(instance)
+	served := make(map[string]bool)
+	for _, nts := range instance.Status.NodeTopology {
+		served[nts.NodeName] = true
+	}
+	for idx := range others {
+		if exporterNamespace(&others[idx]) != namespace {
+			continue
+		}
+		for _, nts := range others[idx].Status.NodeTopology {
+			delete(served, nts.NodeName)
+		}
+	}
+	if len(served) == 0 {
+		return nil
+	}
+
+	nrts := nrtv1alpha1.NodeResourceTopologyList{}
+	if err := r.List(ctx, &nrts, client.InNamespace(namespace)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil // no CRD, nothing to delete
+		}
+		return errors.Wrapf(err, "could not list the noderesourcetopology objects")
+	}
+	// one event per node would flood the instance, so report them all at once
+	deleted := 0
+	defer func() {
+		if deleted > 0 {
+			r.events(instance).Normalf(eventReasonDeleted, "deleted %d noderesourcetopology objects", deleted)
+		}
+	}()
+	for idx := range nrts.Items {
+		nrt := &nrts.Items[idx]
+		if !served[nrt.Name] {
+			continue
+		}
+		err := r.Delete(ctx, nrt)
+		if err != nil && !apierrors.IsNotFound(err) {
+			r.events(instance).Warningf(eventReasonDeleteFailed, "could not delete the noderesourcetopology %s: %v", nrt.Name, err)
+			return errors.Wrapf(err, "could not delete the noderesourcetopology %s", client.ObjectKeyFromObject(nrt))
+		}
+		if err == nil {
+			metrics.ObjectWrite(nrtv1alpha1.SchemeGroupVersion.WithKind("NodeResourceTopology"), metrics.VerbDelete)
+			deleted++
+		}
+	}
+	return nil
+}
+
+// exporterNamespace returns the namespace the exporter of the given instance writes the NodeResourceTopology objects to.
+func exporterNamespace(instance *topologyexporterv1beta1.ResourceTopologyExporter) string {
+	if instance.Status.DaemonSet != nil {
+		return instance.Status.DaemonSet.Namespace
+	}
+	return instance.Namespace
+}
+
+// otherInstances returns the instances, besides the given one, which are not being deleted.
+func (r *ResourceTopologyExporterReconciler) otherInstances(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter) ([]topologyexporterv1beta1.ResourceTopologyExporter, error) {
+	instances := topologyexporterv1beta1.ResourceTopologyExporterList{}
+	if err := r.List(ctx, &instances); err != nil {
+		return nil, err
+	}
+	ret := []topologyexporterv1beta1.ResourceTopologyExporter{}
+	for _, other := range instances.Items {
+		if other.UID == instance.UID || other.DeletionTimestamp != nil {
+			continue
+		}
+		ret = append(ret, other)
+	}
+	return ret, nil
+}
+
+func (r *ResourceTopologyExporterReconciler) updateUninstallProgress(ctx context.Context, instance *topologyexporterv1beta1.ResourceTopologyExporter, message string) error {
+	return r.updateStatus(ctx, instance, status.ConditionProgressing, status.ReasonUninstalling, fmt.Sprintf("uninstall policy %s: %s", instance.Spec.UninstallPolicy, message))
+}
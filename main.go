@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -28,8 +29,10 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -41,11 +44,15 @@ import (
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform/detect"
 	"github.com/k8stopologyawareschedwg/deployer/pkg/tlog"
+	nrtv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
 	topologyexporterv1alpha1 "github.com/openshift-kni/rte-operator/api/v1alpha1"
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
 	"github.com/openshift-kni/rte-operator/controllers"
+	"github.com/openshift-kni/rte-operator/pkg/clusterinfo"
 	"github.com/openshift-kni/rte-operator/pkg/images"
+	"github.com/openshift-kni/rte-operator/pkg/render"
+	"github.com/openshift-kni/rte-operator/pkg/validation"
 
-	"github.com/k8stopologyawareschedwg/deployer/pkg/manifests"
 	apimanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/api"
 	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
 	//+kubebuilder:scaffold:imports
@@ -60,17 +67,29 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+	utilruntime.Must(nrtv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(topologyexporterv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(topologyexporterv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == renderCommand {
+		if err := runRender(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", renderCommand, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
 	var platformName string
 	var detectPlatformOnly bool
+	var detectionOutputFormat string
 	var renderManifestsFor string
+	var dryRun bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -78,13 +97,22 @@ func main() {
 			"Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&platformName, "platform", "", "platform to deploy on - leave empty to autodetect")
 	flag.BoolVar(&detectPlatformOnly, "detect-platform-only", false, "detect and report the platform, then exits")
-	flag.StringVar(&renderManifestsFor, "render-manifests-for", "", "outputs the manifests rendered for given namespace, then exits")
+	flag.StringVar(&detectionOutputFormat, "detection-output", detectionOutputText, "format of the --detect-platform-only report: "+detectionOutputText+" for the platform only, "+detectionOutputJSON+" for the platform and the cluster capabilities")
+	flag.StringVar(&renderManifestsFor, "render-manifests-for", "", "outputs the manifests rendered for given namespace, then exits. Deprecated: use the render command")
+	flag.BoolVar(&dryRun, "dry-run", false, "report the changes the operator would make to the cluster instead of making them")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if detectionOutputFormat != detectionOutputText && detectionOutputFormat != detectionOutputJSON {
+		setupLog.Error(fmt.Errorf("unknown detection output %q", detectionOutputFormat), "unable to setup")
+		os.Exit(1)
+	}
+
 	// if it is unknown, it's fine
 	userPlatform, _ := platform.FromString(platformName)
 	plat, err := detectPlatform(setupLog, userPlatform)
@@ -102,7 +130,14 @@ func main() {
 	setupLog.Info("detected cluster", "platform", clusterPlatform)
 
 	if detectPlatformOnly {
-		fmt.Printf("platform=%s\n", clusterPlatform)
+		if detectionOutputFormat == detectionOutputText {
+			fmt.Printf("platform=%s\n", clusterPlatform)
+			os.Exit(0)
+		}
+		if err := reportDetection(plat); err != nil {
+			setupLog.Error(err, "unable to report the detection")
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
@@ -120,18 +155,16 @@ func main() {
 	}
 	setupLog.Info("RTE manifests loaded")
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
-
 	if renderManifestsFor != "" {
-		reconciler := &controllers.ResourceTopologyExporterReconciler{
-			Log:          ctrl.Log.WithName("controllers").WithName("RTE"),
-			APIManifests: apiManifests,
-			RTEManifests: rteManifests,
-			Platform:     clusterPlatform,
-			ImageSpec:    images.ResourceTopologyExporterDefaultImageSHA,
+		instance := &topologyexporterv1beta1.ResourceTopologyExporter{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: renderManifestsFor,
+			},
+		}
+		objs, err := renderExporter(clusterPlatform, instance)
+		if err == nil {
+			err = render.YAML(os.Stdout, objs)
 		}
-
-		err := renderObjects(reconciler.RenderManifests(renderManifestsFor).ToObjects())
 		if err != nil {
 			setupLog.Error(err, "unable to render manifests")
 			os.Exit(1)
@@ -152,26 +185,40 @@ func main() {
 		os.Exit(1)
 	}
 
-	imageSpec, err := images.GetCurrentImage(mgr.GetClient(), context.Background())
+	// the cache is not started yet, so the client can't be used
+	defaultImage, err := images.GetDefaultSelection(mgr.GetAPIReader(), context.Background())
 	if err != nil {
 		// intentionally continue
-		setupLog.Info("unable to find current image, using hardcoded", "error", err)
+		setupLog.Info("unable to find current image", "error", err)
 	}
-	setupLog.Info("using RTE image", "spec", imageSpec)
+	if _, ok := os.LookupEnv(images.EnvVarDeprecatedExporterImage); ok {
+		setupLog.Info("the exporter image environment variable is deprecated", "variable", images.EnvVarDeprecatedExporterImage, "replacement", images.EnvVarRelatedImageExporter)
+	}
+	setupLog.Info("using RTE image", "spec", defaultImage.Image, "source", defaultImage.Source)
 
 	if err = (&controllers.ResourceTopologyExporterReconciler{
-		Client:       mgr.GetClient(),
-		Scheme:       mgr.GetScheme(),
-		Log:          ctrl.Log.WithName("controllers").WithName("RTE"),
-		APIManifests: apiManifests,
-		RTEManifests: rteManifests,
-		Platform:     clusterPlatform,
-		Helper:       deployer.NewHelperWithClient(mgr.GetClient(), "", tlog.NewNullLogAdapter()),
-		ImageSpec:    imageSpec,
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Log:           ctrl.Log.WithName("controllers").WithName("RTE"),
+		APIManifests:  apiManifests,
+		RTEManifests:  rteManifests,
+		Platform:      clusterPlatform,
+		Helper:        deployer.NewHelperWithClient(mgr.GetClient(), "", tlog.NewNullLogAdapter()),
+		DefaultImage:  defaultImage,
+		ImageResolver: images.NewRegistryResolver(mgr.GetAPIReader()),
+		Recorder:      mgr.GetEventRecorderFor("rte-operator"),
+		DryRun:        dryRun,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ResourceTopologyExporter")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&topologyexporterv1beta1.ResourceTopologyExporter{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ResourceTopologyExporter")
+			os.Exit(1)
+		}
+		validation.SetupWebhookWithManager(mgr)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -190,10 +237,44 @@ func main() {
 	}
 }
 
+// Formats of the --detect-platform-only report
+const (
+	detectionOutputText = "text"
+	detectionOutputJSON = "json"
+)
+
 type detectionOutput struct {
 	AutoDetected platform.Platform `json:"auto_detected"`
 	UserSupplied platform.Platform `json:"user_supplied"`
 	Discovered   platform.Platform `json:"discovered"`
+	Cluster      clusterinfo.Info  `json:"cluster"`
+}
+
+// reportDetection writes the detection results, along with the cluster capabilities, as JSON to stdout.
+func reportDetection(do detectionOutput) error {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	disc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	cli, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	do.Cluster, err = clusterinfo.Discover(context.Background(), disc, cli)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(do, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", data)
+	return nil
 }
 
 func detectPlatform(debugLog logr.Logger, userSupplied platform.Platform) (detectionOutput, error) {
@@ -220,14 +301,3 @@ func detectPlatform(debugLog logr.Logger, userSupplied platform.Platform) (detec
 	do.Discovered = do.AutoDetected
 	return do, nil
 }
-
-func renderObjects(objs []client.Object) error {
-	for _, obj := range objs {
-		fmt.Printf("---\n")
-		if err := manifests.SerializeObject(obj, os.Stdout); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
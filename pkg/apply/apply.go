@@ -19,16 +19,26 @@ package apply
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/openshift-kni/rte-operator/pkg/metrics"
 	"github.com/openshift-kni/rte-operator/pkg/objectstate"
 )
 
+// FieldManager is the field manager the operator writes the objects it manages with.
+const FieldManager = "rte-operator"
+
+var conflictManagerRegex = regexp.MustCompile(`conflict with "([^"]+)"`)
+
 func describeObject(obj client.Object) (string, error) {
 	name := obj.GetName()
 	namespace := obj.GetNamespace()
@@ -40,16 +50,24 @@ func describeObject(obj client.Object) (string, error) {
 	return fmt.Sprintf("(%s) %s/%s", gvk.String(), namespace, name), nil
 }
 
-func ApplyObject(ctx context.Context, log logr.Logger, client k8sclient.Client, objState objectstate.ObjectState) (client.Object, error) {
+// ApplyObject creates or updates the object as the given state requires, and reports the changes it makes through the events.
+func ApplyObject(ctx context.Context, log logr.Logger, client k8sclient.Client, events Events, objState objectstate.ObjectState) (client.Object, error) {
+	if objState.Mode == objectstate.ApplyModeServerSide {
+		return applyServerSide(ctx, log, client, events, objState)
+	}
+
 	objDesc, _ := describeObject(objState.Desired)
 
 	if objState.IsNotFoundError() {
 		log.Info("creating", "object", objDesc)
-		err := client.Create(ctx, objState.Desired)
+		err := client.Create(ctx, objState.Desired, k8sclient.FieldOwner(FieldManager))
 		if err != nil {
+			events.Warningf(EventReasonCreateFailed, "could not create %s: %v", objDesc, err)
 			return nil, err
 		}
 		log.Info("created", "object", objDesc)
+		metrics.ObjectWrite(objState.Desired.GetObjectKind().GroupVersionKind(), metrics.VerbCreate)
+		events.Normalf(EventReasonCreated, "created %s", objDesc)
 		return objState.Desired, nil
 	}
 
@@ -64,10 +82,98 @@ func ApplyObject(ctx context.Context, log logr.Logger, client k8sclient.Client,
 	}
 	if !ok {
 		log.Info("updating", "object", objDesc)
-		if err := client.Update(ctx, updated); err != nil {
+		if err := client.Update(ctx, updated, k8sclient.FieldOwner(FieldManager)); err != nil {
+			events.Warningf(EventReasonUpdateFailed, "could not update %s: %v", objDesc, err)
 			return nil, errors.Wrapf(err, "could not update object %s", objDesc)
 		}
 		log.Info("updated", "object", objDesc)
+		metrics.ObjectWrite(objState.Desired.GetObjectKind().GroupVersionKind(), metrics.VerbUpdate)
+		events.updated(objDesc, objState.Existing, updated)
 	}
 	return updated, nil
 }
+
+// applyServerSide applies the desired object, creating it if needed. The operator owns the fields it sets:
+// the changes other field managers made to them are reported, and reverted.
+func applyServerSide(ctx context.Context, log logr.Logger, client k8sclient.Client, events Events, objState objectstate.ObjectState) (client.Object, error) {
+	objDesc, _ := describeObject(objState.Desired)
+
+	desired := objState.Desired
+	if objState.Error == nil && objState.Merge != nil {
+		var err error
+		desired, err = objState.Merge(objState.Existing, objState.Desired)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not merge object %s with existing", objDesc)
+		}
+	}
+
+	// even no-op applies are writes, so skip them
+	if objState.Error == nil && objState.Compare != nil {
+		ok, err := objState.Compare(objState.Existing, desired)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not compare object %s with existing", objDesc)
+		}
+		if ok {
+			return objState.Existing, nil
+		}
+	}
+
+	log.Info("applying", "object", objDesc)
+	obj := applyConfiguration(desired)
+	err := client.Patch(ctx, obj, k8sclient.Apply, k8sclient.FieldOwner(FieldManager))
+	if apierrors.IsConflict(err) {
+		managers := conflictingManagers(err)
+		log.Info("taking over fields", "object", objDesc, "managers", managers)
+		obj = applyConfiguration(desired)
+		err = client.Patch(ctx, obj, k8sclient.Apply, k8sclient.FieldOwner(FieldManager), k8sclient.ForceOwnership)
+		if err == nil {
+			events.conflict(objDesc, managers)
+		}
+	}
+	if err != nil {
+		if objState.IsNotFoundError() {
+			events.Warningf(EventReasonCreateFailed, "could not create %s: %v", objDesc, err)
+		} else {
+			events.Warningf(EventReasonUpdateFailed, "could not update %s: %v", objDesc, err)
+		}
+		return nil, errors.Wrapf(err, "could not apply object %s", objDesc)
+	}
+	log.Info("applied", "object", objDesc)
+	metrics.ObjectWrite(objState.Desired.GetObjectKind().GroupVersionKind(), metrics.VerbApply)
+	if objState.IsNotFoundError() {
+		events.Normalf(EventReasonCreated, "created %s", objDesc)
+	} else {
+		events.updated(objDesc, objState.Existing, desired)
+	}
+	return obj, nil
+}
+
+// applyConfiguration returns a copy of the desired object without the fields server-side apply rejects.
+func applyConfiguration(desired client.Object) client.Object {
+	obj := desired.DeepCopyObject().(client.Object)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
+	return obj
+}
+
+// conflictingManagers extracts the field managers owning the conflicting fields from a server-side apply error.
+func conflictingManagers(err error) []string {
+	var statusErr *apierrors.StatusError
+	if !errors.As(err, &statusErr) || statusErr.ErrStatus.Details == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	managers := []string{}
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		match := conflictManagerRegex.FindStringSubmatch(cause.Message)
+		if match == nil || seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		managers = append(managers, match[1])
+	}
+	return managers
+}
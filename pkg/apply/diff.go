@@ -0,0 +1,215 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
+)
+
+// DiffObject returns the unified diff, in YAML format, of the changes ApplyObject would make
+// for the given object state, without changing anything. Returns an empty diff if the object is up to date.
+// Only the fields the operator sets are reported, the ones set by other actors are left out.
+func DiffObject(objState objectstate.ObjectState) (string, error) {
+	objDesc, _ := describeObject(objState.Desired)
+
+	if objState.IsNotFoundError() {
+		return unifiedDiff(objDesc, nil, objState.Desired)
+	}
+
+	desired := objState.Desired.DeepCopyObject().(client.Object)
+	if objState.Merge != nil {
+		// Merge the desired object with what actually exists, like ApplyObject does
+		updated, err := objState.Merge(objState.Existing.DeepCopyObject().(client.Object), desired)
+		if err != nil {
+			return "", errors.Wrapf(err, "could not merge object %s with existing", objDesc)
+		}
+		desired = updated
+	}
+	ok, err := objState.Compare(objState.Existing, desired)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not compare object %s with existing", objDesc)
+	}
+	if ok {
+		return "", nil
+	}
+	return unifiedDiff(objDesc, objState.Existing, desired)
+}
+
+// DeletionDiff returns the unified diff, in YAML format, of the deletion of the given object.
+func DeletionDiff(obj client.Object) (string, error) {
+	objDesc, _ := describeObject(obj)
+	return unifiedDiff(objDesc, obj, nil)
+}
+
+func unifiedDiff(objDesc string, existing, desired client.Object) (string, error) {
+	desData, err := toDiffableData(desired)
+	if err != nil {
+		return "", err
+	}
+	exData, err := toDiffableData(existing)
+	if err != nil {
+		return "", err
+	}
+	if exData != nil && desData != nil {
+		exData = project(exData, desData).(map[string]interface{})
+	}
+
+	exText, err := toYAML(exData)
+	if err != nil {
+		return "", err
+	}
+	desText, err := toYAML(desData)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(exText),
+		B:        difflib.SplitLines(desText),
+		FromFile: "live " + objDesc,
+		ToFile:   "desired " + objDesc,
+		Context:  3,
+	})
+}
+
+// changedFields returns the paths of the fields the operator sets which differ between the given objects,
+// which must be both set. Fields in lists are reported as the path of the list.
+func changedFields(existing, desired client.Object) ([]string, error) {
+	desData, err := toDiffableData(desired)
+	if err != nil {
+		return nil, err
+	}
+	exData, err := toDiffableData(existing)
+	if err != nil {
+		return nil, err
+	}
+	return changedPaths("", project(exData, desData), desData, nil), nil
+}
+
+func changedPaths(path string, existing, desired interface{}, paths []string) []string {
+	exVal, exOk := existing.(map[string]interface{})
+	desVal, desOk := desired.(map[string]interface{})
+	if !exOk || !desOk {
+		if !reflect.DeepEqual(existing, desired) {
+			paths = append(paths, path)
+		}
+		return paths
+	}
+	keys := make([]string, 0, len(desVal))
+	for key := range desVal {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		subPath := key
+		if path != "" {
+			subPath = path + "." + key
+		}
+		paths = changedPaths(subPath, exVal[key], desVal[key], paths)
+	}
+	return paths
+}
+
+func toDiffableData(obj client.Object) (map[string]interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	// either not ours or not interesting; the type is reported in the diff header
+	for _, field := range []string{"apiVersion", "kind", "status"} {
+		delete(data, field)
+	}
+	if metadata, ok := data["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "managedFields")
+	}
+	dropNulls(data)
+	return data, nil
+}
+
+// dropNulls removes the unset fields, like the creationTimestamp of the desired objects,
+// which would otherwise be reported as changed.
+func dropNulls(data interface{}) {
+	switch val := data.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			if item == nil {
+				delete(val, key)
+				continue
+			}
+			dropNulls(item)
+		}
+	case []interface{}:
+		for _, item := range val {
+			dropNulls(item)
+		}
+	}
+}
+
+func toYAML(data map[string]interface{}) (string, error) {
+	if data == nil {
+		return "", nil
+	}
+	text, err := yaml.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+// project returns the existing data restricted to the fields set in the desired data.
+// Lists of different length are returned unchanged, as the diff must show them all.
+func project(existing, desired interface{}) interface{} {
+	switch desVal := desired.(type) {
+	case map[string]interface{}:
+		exVal, ok := existing.(map[string]interface{})
+		if !ok {
+			return existing
+		}
+		ret := make(map[string]interface{})
+		for key, val := range desVal {
+			if exItem, ok := exVal[key]; ok {
+				ret[key] = project(exItem, val)
+			}
+		}
+		return ret
+	case []interface{}:
+		exVal, ok := existing.([]interface{})
+		if !ok || len(exVal) != len(desVal) {
+			return existing
+		}
+		ret := make([]interface{}, len(exVal))
+		for idx := range exVal {
+			ret[idx] = project(exVal[idx], desVal[idx])
+		}
+		return ret
+	default:
+		return existing
+	}
+}
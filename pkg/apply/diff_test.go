@@ -0,0 +1,168 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate/compare"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate/merge"
+)
+
+func makeDaemonSet(image string) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "rte",
+			Name:      "resource-topology-exporter",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "resource-topology-exporter-container", Image: image},
+					},
+				},
+			},
+		},
+	}
+}
+
+// liveDaemonSet mimics the object read from the cluster, with server-side defaults and metadata
+func liveDaemonSet(image string) *appsv1.DaemonSet {
+	ds := makeDaemonSet(image)
+	ds.TypeMeta = metav1.TypeMeta{}
+	ds.ResourceVersion = "42"
+	ds.UID = "uid-1234"
+	ds.CreationTimestamp = metav1.Now()
+	ds.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirst
+	ds.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+	ds.Status.NumberReady = 3
+	return ds
+}
+
+func TestDiffObject(t *testing.T) {
+	type testCase struct {
+		description     string
+		objState        objectstate.ObjectState
+		expectedEmpty   bool
+		expectedAdded   []string
+		expectedRemoved []string
+	}
+
+	notFound := apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, "resource-topology-exporter")
+
+	testCases := []testCase{
+		{
+			description: "up to date",
+			objState: objectstate.ObjectState{
+				Existing: liveDaemonSet("quay.io/rte:v1"),
+				Desired:  makeDaemonSet("quay.io/rte:v1"),
+				Compare:  compare.DesiredFields,
+				Merge:    merge.MetadataForUpdate,
+			},
+			expectedEmpty: true,
+		},
+		{
+			description: "image change",
+			objState: objectstate.ObjectState{
+				Existing: liveDaemonSet("quay.io/rte:v1"),
+				Desired:  makeDaemonSet("quay.io/rte:v2"),
+				Compare:  compare.DesiredFields,
+				Merge:    merge.MetadataForUpdate,
+			},
+			expectedAdded:   []string{"+      - image: quay.io/rte:v2"},
+			expectedRemoved: []string{"-      - image: quay.io/rte:v1"},
+		},
+		{
+			description: "image change, server side",
+			objState: objectstate.ObjectState{
+				Existing: liveDaemonSet("quay.io/rte:v1"),
+				Desired:  makeDaemonSet("quay.io/rte:v2"),
+				Compare:  compare.DesiredFields,
+				Merge:    merge.DaemonSetForApply,
+				Mode:     objectstate.ApplyModeServerSide,
+			},
+			expectedAdded:   []string{"+      - image: quay.io/rte:v2"},
+			expectedRemoved: []string{"-      - image: quay.io/rte:v1"},
+		},
+		{
+			description: "creation",
+			objState: objectstate.ObjectState{
+				Error:   notFound,
+				Desired: makeDaemonSet("quay.io/rte:v1"),
+				Compare: compare.DesiredFields,
+				Merge:   merge.MetadataForUpdate,
+			},
+			expectedAdded: []string{"+      - image: quay.io/rte:v1", "+  name: resource-topology-exporter"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			diff, err := DiffObject(tc.objState)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expectedEmpty {
+				if diff != "" {
+					t.Errorf("expected no diff, got:\n%s", diff)
+				}
+				return
+			}
+			lines := strings.Split(diff, "\n")
+			for _, line := range append(tc.expectedAdded, tc.expectedRemoved...) {
+				if !containsLine(lines, line) {
+					t.Errorf("missing line %q in diff:\n%s", line, diff)
+				}
+			}
+			// the fields not set by the operator must not pollute the diff
+			for _, line := range lines {
+				if strings.Contains(line, "dnsPolicy") || strings.Contains(line, "resourceVersion") || strings.Contains(line, "numberReady") || strings.Contains(line, "creationTimestamp") {
+					t.Errorf("unexpected line %q in diff:\n%s", line, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDeletionDiff(t *testing.T) {
+	diff, err := DeletionDiff(makeDaemonSet("quay.io/rte:v1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !containsLine(strings.Split(diff, "\n"), "-      - image: quay.io/rte:v1") {
+		t.Errorf("unexpected deletion diff:\n%s", diff)
+	}
+}
+
+func containsLine(lines []string, line string) bool {
+	for _, cur := range lines {
+		if cur == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Event reasons for the changes ApplyObject makes
+const (
+	EventReasonCreated       = "Created"
+	EventReasonCreateFailed  = "CreateFailed"
+	EventReasonUpdated       = "Updated"
+	EventReasonUpdateFailed  = "UpdateFailed"
+	EventReasonApplyConflict = "ApplyConflict"
+)
+
+// maxReportedFields keeps the events short enough to read in `kubectl describe`
+const maxReportedFields = 8
+
+// Events reports the changes made on behalf of Object, like the instance owning the applied objects.
+// The zero value reports nothing.
+type Events struct {
+	Recorder record.EventRecorder
+	Object   runtime.Object
+	// Conflict, if set, is told about the objects whose fields were taken back from the given field managers
+	Conflict func(objDesc string, managers []string)
+}
+
+// Normalf emits a Normal event about Object.
+func (ev Events) Normalf(reason, messageFmt string, args ...interface{}) {
+	ev.eventf(corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+// Warningf emits a Warning event about Object.
+func (ev Events) Warningf(reason, messageFmt string, args ...interface{}) {
+	ev.eventf(corev1.EventTypeWarning, reason, messageFmt, args...)
+}
+
+func (ev Events) eventf(eventType, reason, messageFmt string, args ...interface{}) {
+	if ev.Recorder == nil || ev.Object == nil {
+		return
+	}
+	ev.Recorder.Eventf(ev.Object, eventType, reason, messageFmt, args...)
+}
+
+// conflict reports the fields of the given object taken back from other field managers.
+func (ev Events) conflict(objDesc string, managers []string) {
+	ev.Warningf(EventReasonApplyConflict, "reverting the changes to %s made by %v", objDesc, managers)
+	if ev.Conflict != nil {
+		ev.Conflict(objDesc, managers)
+	}
+}
+
+// updated reports the update of the given object, along with the fields which changed.
+func (ev Events) updated(objDesc string, existing, updated client.Object) {
+	fields, err := changedFields(existing, updated)
+	if err != nil || len(fields) == 0 {
+		ev.Normalf(EventReasonUpdated, "updated %s", objDesc)
+		return
+	}
+	ev.Normalf(EventReasonUpdated, "updated %s: changed %s", objDesc, summarizeFields(fields))
+}
+
+func summarizeFields(fields []string) string {
+	if len(fields) <= maxReportedFields {
+		return strings.Join(fields, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(fields[:maxReportedFields], ", "), len(fields)-maxReportedFields)
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apply
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate/compare"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate/merge"
+)
+
+// stubClient serves the writes ApplyObject makes in update mode, failing them if told so
+type stubClient struct {
+	client.Client
+	err error
+}
+
+func (sc *stubClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return sc.err
+}
+
+func (sc *stubClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return sc.err
+}
+
+func TestApplyObjectEvents(t *testing.T) {
+	type testCase struct {
+		description   string
+		objState      objectstate.ObjectState
+		err           error
+		expectedEvent string
+	}
+
+	notFound := apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, "resource-topology-exporter")
+	objDesc := "(apps/v1, Kind=DaemonSet) rte/resource-topology-exporter"
+
+	testCases := []testCase{
+		{
+			description: "creation",
+			objState: objectstate.ObjectState{
+				Error:   notFound,
+				Desired: makeDaemonSet("quay.io/rte:v1"),
+			},
+			expectedEvent: "Normal Created created " + objDesc,
+		},
+		{
+			description: "creation failure",
+			objState: objectstate.ObjectState{
+				Error:   notFound,
+				Desired: makeDaemonSet("quay.io/rte:v1"),
+			},
+			err:           fmt.Errorf("forbidden"),
+			expectedEvent: "Warning CreateFailed could not create " + objDesc + ": forbidden",
+		},
+		{
+			description: "update",
+			objState: objectstate.ObjectState{
+				Existing: liveDaemonSet("quay.io/rte:v1"),
+				Desired:  makeDaemonSet("quay.io/rte:v2"),
+				Compare:  compare.DesiredFields,
+				Merge:    merge.MetadataForUpdate,
+			},
+			expectedEvent: "Normal Updated updated " + objDesc + ": changed spec.template.spec.containers",
+		},
+		{
+			description: "update failure",
+			objState: objectstate.ObjectState{
+				Existing: liveDaemonSet("quay.io/rte:v1"),
+				Desired:  makeDaemonSet("quay.io/rte:v2"),
+				Compare:  compare.DesiredFields,
+				Merge:    merge.MetadataForUpdate,
+			},
+			err:           fmt.Errorf("forbidden"),
+			expectedEvent: "Warning UpdateFailed could not update " + objDesc + ": forbidden",
+		},
+		{
+			description: "up to date",
+			objState: objectstate.ObjectState{
+				Existing: liveDaemonSet("quay.io/rte:v1"),
+				Desired:  makeDaemonSet("quay.io/rte:v1"),
+				Compare:  compare.DesiredFields,
+				Merge:    merge.MetadataForUpdate,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			events := Events{Recorder: recorder, Object: makeDaemonSet("owner")}
+			_, err := ApplyObject(context.TODO(), logr.Discard(), &stubClient{err: tc.err}, events, tc.objState)
+			if (err != nil) != (tc.err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := ""
+			select {
+			case got = <-recorder.Events:
+			default:
+			}
+			if got != tc.expectedEvent {
+				t.Errorf("expected event %q got %q", tc.expectedEvent, got)
+			}
+		})
+	}
+}
+
+func TestEventsZeroValue(t *testing.T) {
+	// must not panic
+	Events{}.Normalf(EventReasonCreated, "created %s", "something")
+}
+
+func TestSummarizeFields(t *testing.T) {
+	fields := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	expected := "a, b, c, d, e, f, g, h and 2 more"
+	if got := summarizeFields(fields); got != expected {
+		t.Errorf("expected %q got %q", expected, got)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package clusterinfo
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ClusterVersionGVK is the OpenShift ClusterVersion, which tells the OpenShift release of the cluster.
+var ClusterVersionGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ClusterVersion"}
+
+// clusterVersionName is the name of the only ClusterVersion object of the cluster.
+const clusterVersionName = "version"
+
+// getAllocatableDefaultOn is the first Kubernetes version enabling by default the KubeletPodResourcesGetAllocatable
+// feature gate, which the exporter needs to report the allocatable resources. The gate was alpha, hence off by default, before.
+var getAllocatableDefaultOn = version.MustParseGeneric("1.23.0")
+
+// Info describes the cluster, as far as the exporter is concerned.
+type Info struct {
+	// KubernetesVersion is the version of the API server.
+	KubernetesVersion string `json:"kubernetes_version"`
+	// ClusterVersion is the OpenShift release if the cluster has a ClusterVersion, the Kubernetes version otherwise.
+	ClusterVersion string `json:"cluster_version"`
+	// HasClusterVersion tells if the cluster serves the config.openshift.io ClusterVersion.
+	HasClusterVersion bool `json:"has_cluster_version"`
+	// GetAllocatableResourcesLikelyEnabled tells if the kubelets likely serve the podresources GetAllocatableResources
+	// endpoint. This is a guess from the Kubernetes version, as the kubelet feature gates can't be read from the API.
+	GetAllocatableResourcesLikelyEnabled bool `json:"get_allocatable_resources_likely_enabled"`
+}
+
+// Discover returns the Info of the cluster, reading the ClusterVersion through the given reader if the cluster serves it.
+func Discover(ctx context.Context, disc discovery.DiscoveryInterface, reader client.Reader) (Info, error) {
+	info := Info{}
+	serverVersion, err := disc.ServerVersion()
+	if err != nil {
+		return info, fmt.Errorf("could not get the server version: %w", err)
+	}
+	info.KubernetesVersion = serverVersion.GitVersion
+	info.ClusterVersion = serverVersion.GitVersion
+	info.GetAllocatableResourcesLikelyEnabled = GetAllocatableResourcesLikelyEnabled(serverVersion.GitVersion)
+
+	info.HasClusterVersion, err = servesKind(disc, ClusterVersionGVK)
+	if err != nil {
+		return info, err
+	}
+	if !info.HasClusterVersion {
+		return info, nil
+	}
+	release, err := openShiftRelease(ctx, reader)
+	if err != nil {
+		return info, err
+	}
+	if release != "" {
+		info.ClusterVersion = release
+	}
+	return info, nil
+}
+
+// GetAllocatableResourcesLikelyEnabled tells if the kubelets of the given version enable by default
+// the podresources GetAllocatableResources endpoint.
+func GetAllocatableResourcesLikelyEnabled(kubeVersion string) bool {
+	ver, err := version.ParseGeneric(kubeVersion)
+	if err != nil {
+		return false
+	}
+	return ver.AtLeast(getAllocatableDefaultOn)
+}
+
+// servesKind tells if the API server serves the given kind.
+func servesKind(disc discovery.DiscoveryInterface, gvk schema.GroupVersionKind) (bool, error) {
+	groups, err := disc.ServerGroups()
+	if err != nil {
+		return false, fmt.Errorf("could not list the API groups: %w", err)
+	}
+	found := false
+	for _, group := range groups.Groups {
+		if group.Name != gvk.Group {
+			continue
+		}
+		for _, ver := range group.Versions {
+			if ver.Version == gvk.Version {
+				found = true
+			}
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	resources, err := disc.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+	if err != nil {
+		return false, fmt.Errorf("could not list the %s resources: %w", gvk.GroupVersion(), err)
+	}
+	for _, res := range resources.APIResources {
+		if res.Kind == gvk.Kind {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// openShiftRelease returns the OpenShift release the cluster runs or is updating to, or empty if not known yet.
+func openShiftRelease(ctx context.Context, reader client.Reader) (string, error) {
+	cv := unstructured.Unstructured{}
+	cv.SetGroupVersionKind(ClusterVersionGVK)
+	if err := reader.Get(ctx, client.ObjectKey{Name: clusterVersionName}, &cv); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("could not get the cluster version: %w", err)
+	}
+	release, _, err := unstructured.NestedString(cv.Object, "status", "desired", "version")
+	return release, err
+}
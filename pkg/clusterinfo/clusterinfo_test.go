@@ -0,0 +1,146 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package clusterinfo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stubReader serves the given ClusterVersion, if any
+type stubReader struct {
+	clusterVersion *unstructured.Unstructured
+}
+
+func (sr stubReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if sr.clusterVersion == nil || key.Name != clusterVersionName {
+		return apierrors.NewNotFound(schema.GroupResource{Group: "config.openshift.io", Resource: "clusterversions"}, key.Name)
+	}
+	sr.clusterVersion.DeepCopyInto(obj.(*unstructured.Unstructured))
+	return nil
+}
+
+func (sr stubReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return fmt.Errorf("not implemented")
+}
+
+func newDiscovery(gitVersion string, resources ...*metav1.APIResourceList) *fakediscovery.FakeDiscovery {
+	return &fakediscovery.FakeDiscovery{
+		Fake:               &clienttesting.Fake{Resources: resources},
+		FakedServerVersion: &version.Info{GitVersion: gitVersion},
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	type testCase struct {
+		description string
+		disc        *fakediscovery.FakeDiscovery
+		reader      stubReader
+		expected    Info
+	}
+
+	configResources := &metav1.APIResourceList{
+		GroupVersion: "config.openshift.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "clusterversions", Kind: "ClusterVersion"},
+		},
+	}
+	clusterVersion := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "config.openshift.io/v1",
+			"kind":       "ClusterVersion",
+			"metadata": map[string]interface{}{
+				"name": "version",
+			},
+			"status": map[string]interface{}{
+				"desired": map[string]interface{}{
+					"version": "4.9.7",
+				},
+			},
+		},
+	}
+
+	testCases := []testCase{
+		{
+			description: "kubernetes",
+			disc:        newDiscovery("v1.23.1"),
+			expected: Info{
+				KubernetesVersion:                    "v1.23.1",
+				ClusterVersion:                       "v1.23.1",
+				GetAllocatableResourcesLikelyEnabled: true,
+			},
+		},
+		{
+			description: "openshift",
+			disc:        newDiscovery("v1.22.3+e790d7f", configResources),
+			reader:      stubReader{clusterVersion: clusterVersion},
+			expected: Info{
+				KubernetesVersion: "v1.22.3+e790d7f",
+				ClusterVersion:    "4.9.7",
+				HasClusterVersion: true,
+			},
+		},
+		{
+			description: "openshift, no ClusterVersion object yet",
+			disc:        newDiscovery("v1.22.3+e790d7f", configResources),
+			expected: Info{
+				KubernetesVersion: "v1.22.3+e790d7f",
+				ClusterVersion:    "v1.22.3+e790d7f",
+				HasClusterVersion: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := Discover(context.TODO(), tc.disc, tc.reader)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %+v got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetAllocatableResourcesLikelyEnabled(t *testing.T) {
+	testCases := map[string]bool{
+		"v1.20.4":         false,
+		"v1.21.0":         false,
+		"v1.22.3+e790d7f": false,
+		"v1.23.0":         true,
+		"v1.24.2-gke.100": true,
+		"garbage":         false,
+	}
+	for kubeVersion, expected := range testCases {
+		if got := GetAllocatableResourcesLikelyEnabled(kubeVersion); got != expected {
+			t.Errorf("version %q: expected %v got %v", kubeVersion, expected, got)
+		}
+	}
+}
@@ -27,11 +27,13 @@ import (
 )
 
 const (
-	envVarPodNamespace = "MY_POD_NAMESPACE"
-	envVarPodName      = "MY_POD_NAME"
+	envVarPodNamespace   = "MY_POD_NAMESPACE"
+	envVarPodName        = "MY_POD_NAME"
+	envVarContainerName  = "MY_CONTAINER_NAME"
+	defaultContainerName = "manager"
 )
 
-func GetCurrentImage(cli client.Client, ctx context.Context) (string, error) {
+func GetCurrentImage(cli client.Reader, ctx context.Context) (string, error) {
 	podNamespace, ok := os.LookupEnv(envVarPodNamespace)
 	if !ok {
 		// TODO log
@@ -42,10 +44,15 @@ func GetCurrentImage(cli client.Client, ctx context.Context) (string, error) {
 		// TODO log
 		return ResourceTopologyExporterDefaultImageSHA, fmt.Errorf("environment variable not set: %q", envVarPodName)
 	}
-	return GetImageFromPod(cli, ctx, podNamespace, podName, "")
+	// sidecars like kube-rbac-proxy may be injected before the operator container, so it must be found by name
+	containerName, ok := os.LookupEnv(envVarContainerName)
+	if !ok || containerName == "" {
+		containerName = defaultContainerName
+	}
+	return GetImageFromPod(cli, ctx, podNamespace, podName, containerName)
 }
 
-func GetImageFromPod(cli client.Client, ctx context.Context, namespace, podName, containerName string) (string, error) {
+func GetImageFromPod(cli client.Reader, ctx context.Context, namespace, podName, containerName string) (string, error) {
 	key := client.ObjectKey{
 		Namespace: namespace,
 		Name:      podName,
@@ -64,8 +71,12 @@ func GetImageFromPod(cli client.Client, ctx context.Context, namespace, podName,
 	return cnt.Image, nil
 }
 
+// findContainerByName returns the container with the given name. An empty name matches the only container of the pod, if so.
 func findContainerByName(pod *corev1.Pod, containerName string) (*corev1.Container, error) {
 	if containerName == "" {
+		if len(pod.Spec.Containers) != 1 {
+			return nil, fmt.Errorf("no container name given, and %s/%s has %d containers", pod.Namespace, pod.Name, len(pod.Spec.Containers))
+		}
 		return &pod.Spec.Containers[0], nil
 	}
 
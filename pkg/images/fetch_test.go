@@ -0,0 +1,77 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package images
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestFindContainerByName(t *testing.T) {
+	type testCase struct {
+		description   string
+		containers    []corev1.Container
+		containerName string
+		expectedImage string
+		expectedErr   bool
+	}
+
+	testCases := []testCase{
+		{
+			description:   "by name, not first",
+			containers:    []corev1.Container{{Name: "kube-rbac-proxy", Image: "proxy"}, {Name: "manager", Image: "operator"}},
+			containerName: "manager",
+			expectedImage: "operator",
+		},
+		{
+			description:   "missing name",
+			containers:    []corev1.Container{{Name: "kube-rbac-proxy", Image: "proxy"}},
+			containerName: "manager",
+			expectedErr:   true,
+		},
+		{
+			description:   "no name, single container",
+			containers:    []corev1.Container{{Name: "manager", Image: "operator"}},
+			expectedImage: "operator",
+		},
+		{
+			description: "no name, many containers",
+			containers:  []corev1.Container{{Name: "kube-rbac-proxy", Image: "proxy"}, {Name: "manager", Image: "operator"}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: tc.containers}}
+			cnt, err := findContainerByName(pod, tc.containerName)
+			if tc.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, got container %q", cnt.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cnt.Image != tc.expectedImage {
+				t.Errorf("expected image %q got %q", tc.expectedImage, cnt.Image)
+			}
+		})
+	}
+}
@@ -17,15 +17,72 @@
 package images
 
 import (
+	"context"
 	"os"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func init() {
-	if rteImage, ok := os.LookupEnv("TAS_RESOURCE_EXPORTER_IMAGE"); ok {
-		ResourceTopologyExporterImage = rteImage
+// EnvVarRelatedImageExporter sets the exporter image, overriding the one of the operator pod.
+// OLM rewrites the RELATED_IMAGE_* variables to point to the mirrored registries in the disconnected clusters.
+const EnvVarRelatedImageExporter = "RELATED_IMAGE_RESOURCE_TOPOLOGY_EXPORTER"
+
+// EnvVarDeprecatedExporterImage sets the exporter image like EnvVarRelatedImageExporter, which takes precedence.
+// Deprecated: use EnvVarRelatedImageExporter.
+const EnvVarDeprecatedExporterImage = "TAS_RESOURCE_EXPORTER_IMAGE"
+
+// Source tells where an exporter image comes from. The values match the ImageSource of the API.
+type Source string
+
+const (
+	SourceCustomResource Source = "CustomResource"
+	SourceEnvironment    Source = "Environment"
+	SourceOperatorPod    Source = "OperatorPod"
+	SourceDefault        Source = "Default"
+)
+
+// Selection is an exporter image along with its source.
+type Selection struct {
+	Image  string
+	Source Source
+}
+
+// GetDefaultSelection returns the exporter image deployed for the instances not selecting one, in order of precedence:
+// the image set in the environment, by EnvVarRelatedImageExporter then EnvVarDeprecatedExporterImage,
+// the image of the operator pod, the image compiled in the operator.
+// The error, if any, tells why the operator pod image is not used; the returned selection is usable anyway.
+func GetDefaultSelection(cli client.Reader, ctx context.Context) (Selection, error) {
+	if selection, ok := environmentSelection(); ok {
+		return selection, nil
+	}
+	image, err := GetCurrentImage(cli, ctx)
+	if err != nil {
+		return Selection{Image: ResourceTopologyExporterDefaultImageSHA, Source: SourceDefault}, err
+	}
+	return Selection{Image: image, Source: SourceOperatorPod}, nil
+}
+
+// GetOfflineDefaultSelection is GetDefaultSelection when there is no operator pod, like when rendering the manifests offline.
+func GetOfflineDefaultSelection() Selection {
+	if selection, ok := environmentSelection(); ok {
+		return selection
 	}
+	return Selection{Image: ResourceTopologyExporterDefaultImageSHA, Source: SourceDefault}
 }
 
-var (
-	ResourceTopologyExporterImage = ResourceTopologyExporterDefaultImageSHA
-)
+func environmentSelection() (Selection, bool) {
+	for _, name := range []string{EnvVarRelatedImageExporter, EnvVarDeprecatedExporterImage} {
+		if image, ok := os.LookupEnv(name); ok && image != "" {
+			return Selection{Image: image, Source: SourceEnvironment}, true
+		}
+	}
+	return Selection{}, false
+}
+
+// Select returns the given image, if any, selected by the instance, or the default selection otherwise.
+func Select(image string, defaultSelection Selection) Selection {
+	if image != "" {
+		return Selection{Image: image, Source: SourceCustomResource}
+	}
+	return defaultSelection
+}
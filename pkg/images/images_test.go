@@ -0,0 +1,160 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package images
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetDefaultSelection(t *testing.T) {
+	type testCase struct {
+		description string
+		env         map[string]string
+		expected    Selection
+		expectedErr bool
+	}
+
+	const (
+		envImage = "mirror.example.com/rte/exporter@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+		podImage = "quay.io/openshift-kni/rte-operator:test"
+	)
+
+	reader := stubReader{
+		pods: map[client.ObjectKey]corev1.Pod{
+			{Namespace: "rte-operator", Name: "rte-operator-abcde"}: {
+				ObjectMeta: metav1.ObjectMeta{Namespace: "rte-operator", Name: "rte-operator-abcde"},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "manager", Image: podImage}},
+				},
+			},
+			{Namespace: "rte-operator", Name: "rte-operator-sidecar"}: {
+				ObjectMeta: metav1.ObjectMeta{Namespace: "rte-operator", Name: "rte-operator-sidecar"},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "kube-rbac-proxy", Image: "quay.io/brancz/kube-rbac-proxy:v0.8.0"},
+						{Name: "operator", Image: podImage},
+					},
+				},
+			},
+		},
+	}
+
+	testCases := []testCase{
+		{
+			description: "environment wins",
+			env: map[string]string{
+				EnvVarRelatedImageExporter: envImage,
+				envVarPodNamespace:         "rte-operator",
+				envVarPodName:              "rte-operator-abcde",
+			},
+			expected: Selection{Image: envImage, Source: SourceEnvironment},
+		},
+		{
+			description: "deprecated environment",
+			env: map[string]string{
+				EnvVarDeprecatedExporterImage: envImage,
+				envVarPodNamespace:            "rte-operator",
+				envVarPodName:                 "rte-operator-abcde",
+			},
+			expected: Selection{Image: envImage, Source: SourceEnvironment},
+		},
+		{
+			description: "environment wins over the deprecated one",
+			env: map[string]string{
+				EnvVarRelatedImageExporter:    envImage,
+				EnvVarDeprecatedExporterImage: "quay.io/example/old-exporter:latest",
+			},
+			expected: Selection{Image: envImage, Source: SourceEnvironment},
+		},
+		{
+			description: "operator pod",
+			env: map[string]string{
+				envVarPodNamespace: "rte-operator",
+				envVarPodName:      "rte-operator-abcde",
+			},
+			expected: Selection{Image: podImage, Source: SourceOperatorPod},
+		},
+		{
+			description: "operator pod with a sidecar injected first",
+			env: map[string]string{
+				envVarPodNamespace:  "rte-operator",
+				envVarPodName:       "rte-operator-sidecar",
+				envVarContainerName: "operator",
+			},
+			expected: Selection{Image: podImage, Source: SourceOperatorPod},
+		},
+		{
+			description: "operator pod with a sidecar, wrong container name",
+			env: map[string]string{
+				envVarPodNamespace: "rte-operator",
+				envVarPodName:      "rte-operator-sidecar",
+			},
+			expected:    Selection{Image: ResourceTopologyExporterDefaultImageSHA, Source: SourceDefault},
+			expectedErr: true,
+		},
+		{
+			description: "missing operator pod",
+			env: map[string]string{
+				envVarPodNamespace: "rte-operator",
+				envVarPodName:      "rte-operator-fghij",
+			},
+			expected:    Selection{Image: ResourceTopologyExporterDefaultImageSHA, Source: SourceDefault},
+			expectedErr: true,
+		},
+		{
+			description: "nothing set",
+			expected:    Selection{Image: ResourceTopologyExporterDefaultImageSHA, Source: SourceDefault},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			for _, name := range []string{EnvVarRelatedImageExporter, EnvVarDeprecatedExporterImage, envVarPodNamespace, envVarPodName, envVarContainerName} {
+				t.Setenv(name, tc.env[name])
+			}
+			got, err := GetDefaultSelection(reader, context.TODO())
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error %v got %v", tc.expectedErr, err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %+v got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	defaultSelection := Selection{Image: ResourceTopologyExporterDefaultImageSHA, Source: SourceDefault}
+
+	got := Select("", defaultSelection)
+	if got != defaultSelection {
+		t.Errorf("expected %+v got %+v", defaultSelection, got)
+	}
+
+	expected := Selection{Image: "quay.io/example/exporter:v1", Source: SourceCustomResource}
+	got = Select("quay.io/example/exporter:v1", defaultSelection)
+	if got != expected {
+		t.Errorf("expected %+v got %+v", expected, got)
+	}
+}
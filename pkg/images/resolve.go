@@ -0,0 +1,378 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package images
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	digest "github.com/opencontainers/go-digest"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Resolver resolves the image tags to digests.
+type Resolver interface {
+	// Resolve returns the given image by digest, using the given pull secrets, in the given namespace,
+	// to access the registry. Images already given by digest are returned unchanged.
+	Resolve(ctx context.Context, image, namespace string, pullSecrets []corev1.LocalObjectReference) (string, error)
+}
+
+// manifestMediaTypes are the manifest formats accepted from the registries. The lists come first,
+// so the digest is the same the container runtimes resolve, whatever the node architecture.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+const (
+	dockerHubDomain   = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+)
+
+// RegistryResolver resolves the image tags querying the registries through the Docker Registry HTTP API V2.
+type RegistryResolver struct {
+	// Reader reads the pull secrets
+	Reader client.Reader
+	// Client queries the registries
+	Client *http.Client
+}
+
+// ResolveTimeout bounds the time spent querying a registry, so an unreachable one doesn't stall the reconciliation.
+const ResolveTimeout = 10 * time.Second
+
+// NewRegistryResolver returns a RegistryResolver reading the pull secrets through the given reader.
+func NewRegistryResolver(reader client.Reader) *RegistryResolver {
+	return &RegistryResolver{
+		Reader: reader,
+		Client: &http.Client{Timeout: ResolveTimeout},
+	}
+}
+
+// Resolve implements Resolver.
+func (rr *RegistryResolver) Resolve(ctx context.Context, image, namespace string, pullSecrets []corev1.LocalObjectReference) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return image, nil
+	}
+	tagged, ok := reference.TagNameOnly(named).(reference.Tagged)
+	if !ok {
+		return "", fmt.Errorf("image %q has neither tag nor digest", image)
+	}
+
+	domain := reference.Domain(named)
+	creds, err := rr.credentials(ctx, domain, namespace, pullSecrets)
+	if err != nil {
+		return "", err
+	}
+	dgst, err := rr.manifestDigest(ctx, registryHost(domain), reference.Path(named), tagged.Tag(), creds)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve image %q: %w", image, err)
+	}
+	byDigest, err := reference.WithDigest(reference.TrimNamed(named), dgst)
+	if err != nil {
+		return "", err
+	}
+	return byDigest.String(), nil
+}
+
+// registryCredentials are the credentials to access a registry. The zero value means anonymous access.
+type registryCredentials struct {
+	username string
+	password string
+}
+
+func (rc registryCredentials) isSet() bool {
+	return rc.username != "" || rc.password != ""
+}
+
+// manifestDigest returns the digest of the manifest of the given tag, authenticating as the registry requires.
+func (rr *RegistryResolver) manifestDigest(ctx context.Context, host, repo, tag string, creds registryCredentials) (digest.Digest, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repo, tag)
+	resp, err := rr.getManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		drain(resp)
+		authorization, err := rr.authorize(ctx, challenge, repo, creds)
+		if err != nil {
+			return "", err
+		}
+		resp, err = rr.getManifest(ctx, manifestURL, authorization)
+		if err != nil {
+			return "", err
+		}
+	}
+	defer drain(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry replied %q to %s", resp.Status, manifestURL)
+	}
+	if dgst := resp.Header.Get("Docker-Content-Digest"); dgst != "" {
+		return digest.Parse(dgst)
+	}
+	// the header is optional, the digest of the manifest is not
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(data), nil
+}
+
+func (rr *RegistryResolver) getManifest(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return rr.Client.Do(req)
+}
+
+// authorize returns the Authorization header value answering the given challenge.
+func (rr *RegistryResolver) authorize(ctx context.Context, challenge, repo string, creds registryCredentials) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !creds.isSet() {
+			return "", fmt.Errorf("registry requires credentials, but no pull secret provides them")
+		}
+		return "Basic " + basicAuth(creds), nil
+	case "bearer":
+		token, err := rr.fetchToken(ctx, params, repo, creds)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+	return "", fmt.Errorf("unsupported registry authentication challenge %q", challenge)
+}
+
+// fetchToken gets from the token service of the registry a token allowing to pull from the given repository.
+func (rr *RegistryResolver) fetchToken(ctx context.Context, params map[string]string, repo string, creds registryCredentials) (string, error) {
+	realm, ok := params["realm"]
+	if !ok {
+		return "", fmt.Errorf("registry authentication challenge without realm")
+	}
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", repo))
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds.isSet() {
+		req.SetBasicAuth(creds.username, creds.password)
+	}
+	resp, err := rr.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer drain(resp)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service replied %q", resp.Status)
+	}
+	tokenResp := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", err
+	}
+	if tokenResp.Token != "" {
+		return tokenResp.Token, nil
+	}
+	if tokenResp.AccessToken != "" {
+		return tokenResp.AccessToken, nil
+	}
+	return "", fmt.Errorf("token service replied with no token")
+}
+
+// credentials returns the credentials for the given registry domain found in the pull secrets.
+func (rr *RegistryResolver) credentials(ctx context.Context, domain, namespace string, pullSecrets []corev1.LocalObjectReference) (registryCredentials, error) {
+	for _, ref := range pullSecrets {
+		secret := corev1.Secret{}
+		if err := rr.Reader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+			return registryCredentials{}, fmt.Errorf("could not read the pull secret %s/%s: %w", namespace, ref.Name, err)
+		}
+		auths, err := dockerConfigAuths(&secret)
+		if err != nil {
+			return registryCredentials{}, fmt.Errorf("could not parse the pull secret %s/%s: %w", namespace, ref.Name, err)
+		}
+		for server, entry := range auths {
+			if matchesRegistry(server, domain) {
+				return entry.credentials()
+			}
+		}
+	}
+	return registryCredentials{}, nil
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+func (dce dockerConfigEntry) credentials() (registryCredentials, error) {
+	if dce.Auth == "" {
+		return registryCredentials{username: dce.Username, password: dce.Password}, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(dce.Auth)
+	if err != nil {
+		return registryCredentials{}, err
+	}
+	items := strings.SplitN(string(data), ":", 2)
+	if len(items) != 2 {
+		return registryCredentials{}, fmt.Errorf("malformed auth entry")
+	}
+	return registryCredentials{username: items[0], password: items[1]}, nil
+}
+
+// dockerConfigAuths returns the registry credentials of the given pull secret, in either the .dockerconfigjson or the legacy .dockercfg format.
+func dockerConfigAuths(secret *corev1.Secret) (map[string]dockerConfigEntry, error) {
+	if data, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+		config := struct {
+			Auths map[string]dockerConfigEntry `json:"auths"`
+		}{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+		return config.Auths, nil
+	}
+	if data, ok := secret.Data[corev1.DockerConfigKey]; ok {
+		auths := map[string]dockerConfigEntry{}
+		if err := json.Unmarshal(data, &auths); err != nil {
+			return nil, err
+		}
+		return auths, nil
+	}
+	return nil, fmt.Errorf("no %q or %q key", corev1.DockerConfigJsonKey, corev1.DockerConfigKey)
+}
+
+// matchesRegistry tells if the server of a pull secret entry, which may be given as URL, is the given registry domain.
+func matchesRegistry(server, domain string) bool {
+	host := server
+	if parsed, err := url.Parse(server); err == nil && parsed.Host != "" {
+		host = parsed.Host
+	}
+	host = strings.TrimSuffix(host, "/")
+	if domain == dockerHubDomain {
+		return host == dockerHubDomain || host == "index.docker.io" || host == dockerHubRegistry
+	}
+	return host == domain
+}
+
+func registryHost(domain string) string {
+	if domain == dockerHubDomain {
+		return dockerHubRegistry
+	}
+	return domain
+}
+
+// parseChallenge splits a WWW-Authenticate header value like `Bearer realm="https://auth.example.com/token",service="example"`.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	items := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(items) < 2 {
+		return items[0], params
+	}
+	for _, param := range splitParams(items[1]) {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[strings.ToLower(kv[0])] = unquote(kv[1])
+	}
+	return items[0], params
+}
+
+// splitParams splits the comma separated challenge parameters, honoring the commas within quoted values,
+// like the ones separating the actions in `scope="repository:ns/name:pull,push"`.
+func splitParams(params string) []string {
+	ret := []string{}
+	quoted, escaped := false, false
+	start := 0
+	for idx, char := range params {
+		switch {
+		case escaped:
+			escaped = false
+		case char == '\\' && quoted:
+			escaped = true
+		case char == '"':
+			quoted = !quoted
+		case char == ',' && !quoted:
+			ret = append(ret, params[start:idx])
+			start = idx + 1
+		}
+	}
+	return append(ret, params[start:])
+}
+
+// unquote returns the value of a challenge parameter, given as a token or as a quoted string.
+func unquote(value string) string {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return value
+	}
+	var sb strings.Builder
+	escaped := false
+	for _, char := range value[1 : len(value)-1] {
+		if char == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(char)
+	}
+	return sb.String()
+}
+
+func basicAuth(creds registryCredentials) string {
+	return base64.StdEncoding.EncodeToString([]byte(creds.username + ":" + creds.password))
+}
+
+// drain consumes and closes the response body, so the connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
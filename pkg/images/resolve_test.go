@@ -0,0 +1,299 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// stubReader serves the given secrets and pods
+type stubReader struct {
+	secrets map[client.ObjectKey]corev1.Secret
+	pods    map[client.ObjectKey]corev1.Pod
+}
+
+func (sr stubReader) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	switch dst := obj.(type) {
+	case *corev1.Secret:
+		if secret, ok := sr.secrets[key]; ok {
+			secret.DeepCopyInto(dst)
+			return nil
+		}
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+	case *corev1.Pod:
+		if pod, ok := sr.pods[key]; ok {
+			pod.DeepCopyInto(dst)
+			return nil
+		}
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, key.Name)
+	}
+	return fmt.Errorf("unexpected object %T", obj)
+}
+
+func (sr stubReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return fmt.Errorf("not implemented")
+}
+
+const (
+	testManifest = `{"schemaVersion":2}`
+	testToken    = "test-token"
+)
+
+// newTestRegistry returns a registry serving testManifest for the repository "rte/exporter", tag "v1".
+// When auth is "basic" or "bearer", the registry requires the user "user" with the password "pass".
+func newTestRegistry(t *testing.T, auth string, digestHeader bool) *httptest.Server {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if scope := req.URL.Query().Get("scope"); scope != "repository:rte/exporter:pull" {
+			t.Errorf("unexpected token scope %q", scope)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": testToken})
+	})
+	mux.HandleFunc("/v2/rte/exporter/manifests/", func(w http.ResponseWriter, req *http.Request) {
+		authz := req.Header.Get("Authorization")
+		switch auth {
+		case "basic":
+			if user, pass, ok := req.BasicAuth(); !ok || user != "user" || pass != "pass" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "bearer":
+			if authz != "Bearer "+testToken {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test"`, srv.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		if !strings.Contains(req.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+			t.Errorf("unexpected accept header %q", req.Header.Get("Accept"))
+		}
+		if !strings.HasSuffix(req.URL.Path, "/v1") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if digestHeader {
+			w.Header().Set("Docker-Content-Digest", digest.FromString(testManifest).String())
+		}
+		fmt.Fprint(w, testManifest)
+	})
+	srv = httptest.NewTLSServer(mux)
+	return srv
+}
+
+func pullSecret(host string) corev1.Secret {
+	return corev1.Secret{
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths":{"https://%s":{"auth":"dXNlcjpwYXNz"}}}`, host)),
+		},
+	}
+}
+
+func TestRegistryResolverResolve(t *testing.T) {
+	type testCase struct {
+		description  string
+		auth         string
+		digestHeader bool
+		tag          string
+		pullSecrets  []corev1.LocalObjectReference
+		expectedErr  bool
+	}
+
+	secretRef := []corev1.LocalObjectReference{{Name: "pull-secret"}}
+	testCases := []testCase{
+		{
+			description:  "anonymous",
+			digestHeader: true,
+			tag:          "v1",
+		},
+		{
+			description: "anonymous, digest computed from the manifest",
+			tag:         "v1",
+		},
+		{
+			description:  "basic auth",
+			auth:         "basic",
+			digestHeader: true,
+			tag:          "v1",
+			pullSecrets:  secretRef,
+		},
+		{
+			description:  "bearer token",
+			auth:         "bearer",
+			digestHeader: true,
+			tag:          "v1",
+			pullSecrets:  secretRef,
+		},
+		{
+			description:  "bearer token, no credentials",
+			auth:         "bearer",
+			digestHeader: true,
+			tag:          "v1",
+			expectedErr:  true,
+		},
+		{
+			description:  "missing pull secret",
+			digestHeader: true,
+			tag:          "v1",
+			pullSecrets:  []corev1.LocalObjectReference{{Name: "missing"}},
+			expectedErr:  true,
+		},
+		{
+			description:  "unknown tag",
+			digestHeader: true,
+			tag:          "v2",
+			expectedErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			srv := newTestRegistry(t, tc.auth, tc.digestHeader)
+			defer srv.Close()
+			host := strings.TrimPrefix(srv.URL, "https://")
+
+			rr := RegistryResolver{
+				Reader: stubReader{
+					secrets: map[client.ObjectKey]corev1.Secret{
+						{Namespace: "rte", Name: "pull-secret"}: pullSecret(host),
+					},
+				},
+				Client: srv.Client(),
+			}
+			got, err := rr.Resolve(context.TODO(), host+"/rte/exporter:"+tc.tag, "rte", tc.pullSecrets)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatalf("expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := host + "/rte/exporter@" + digest.FromString(testManifest).String()
+			if got != expected {
+				t.Errorf("expected %q got %q", expected, got)
+			}
+		})
+	}
+}
+
+func TestRegistryResolverResolveByDigest(t *testing.T) {
+	image := "quay.io/rte/exporter@" + digest.FromString(testManifest).String()
+	rr := RegistryResolver{} // must not query anything
+	got, err := rr.Resolve(context.TODO(), image, "rte", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != image {
+		t.Errorf("expected %q got %q", image, got)
+	}
+}
+
+func TestMatchesRegistry(t *testing.T) {
+	type testCase struct {
+		server   string
+		domain   string
+		expected bool
+	}
+
+	testCases := []testCase{
+		{server: "quay.io", domain: "quay.io", expected: true},
+		{server: "https://quay.io/", domain: "quay.io", expected: true},
+		{server: "quay.io:443", domain: "quay.io", expected: false},
+		{server: "https://index.docker.io/v1/", domain: "docker.io", expected: true},
+		{server: "docker.io", domain: "quay.io", expected: false},
+	}
+
+	for _, tc := range testCases {
+		got := matchesRegistry(tc.server, tc.domain)
+		if got != tc.expected {
+			t.Errorf("server %q domain %q: expected %v got %v", tc.server, tc.domain, tc.expected, got)
+		}
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	type testCase struct {
+		challenge      string
+		expectedScheme string
+		expectedParams map[string]string
+	}
+
+	testCases := []testCase{
+		{
+			challenge:      "Basic",
+			expectedScheme: "Basic",
+			expectedParams: map[string]string{},
+		},
+		{
+			challenge:      `Basic realm="Registry Realm"`,
+			expectedScheme: "Basic",
+			expectedParams: map[string]string{"realm": "Registry Realm"},
+		},
+		{
+			challenge:      `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:ns/name:pull,push"`,
+			expectedScheme: "Bearer",
+			expectedParams: map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "registry.example.com",
+				"scope":   "repository:ns/name:pull,push",
+			},
+		},
+		{
+			challenge:      `Bearer Realm="https://auth.example.com/token", error=invalid_token, error_description="say \"hi\", then go"`,
+			expectedScheme: "Bearer",
+			expectedParams: map[string]string{
+				"realm":             "https://auth.example.com/token",
+				"error":             "invalid_token",
+				"error_description": `say "hi", then go`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		scheme, params := parseChallenge(tc.challenge)
+		if scheme != tc.expectedScheme {
+			t.Errorf("challenge %q: expected scheme %q got %q", tc.challenge, tc.expectedScheme, scheme)
+		}
+		if !reflect.DeepEqual(params, tc.expectedParams) {
+			t.Errorf("challenge %q: expected params %v got %v", tc.challenge, tc.expectedParams, params)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+const namespace = "rte_operator"
+
+// Verbs of the object writes
+const (
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbApply  = "apply"
+	VerbDelete = "delete"
+)
+
+var (
+	reconcileResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_results_total",
+		Help:      "Number of reconciliations by resulting condition and reason.",
+	}, []string{"condition", "reason"})
+
+	objectWrites = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "object_writes_total",
+		Help:      "Number of writes to the managed objects by group, version, kind and verb.",
+	}, []string{"group", "version", "kind", "verb"})
+
+	instances = &instanceCollector{
+		conditionStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "condition_status"),
+			"Status of the conditions of the ResourceTopologyExporter instances: 1 if true, 0 otherwise.",
+			[]string{"exporter_namespace", "name", "condition"}, nil,
+		),
+		nodeTopologyStale: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "node_topology_stale"),
+			"Whether the NodeResourceTopology object of the node is stale: 1 if stale, 0 otherwise.",
+			[]string{"exporter_namespace", "name", "node"}, nil,
+		),
+		nodeTopologyAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "node_topology_last_update_age_seconds"),
+			"Seconds since the NodeResourceTopology object of the node was last updated.",
+			[]string{"exporter_namespace", "name", "node"}, nil,
+		),
+		states: make(map[types.NamespacedName]*instanceState),
+	}
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(reconcileResults, objectWrites, instances)
+}
+
+// ReconcileResult counts a reconciliation ending with the given condition and reason.
+func ReconcileResult(condition, reason string) {
+	reconcileResults.WithLabelValues(condition, reason).Inc()
+}
+
+// ObjectWrite counts a write of the given verb to an object of the given kind.
+func ObjectWrite(gvk schema.GroupVersionKind, verb string) {
+	objectWrites.WithLabelValues(gvk.Group, gvk.Version, gvk.Kind, verb).Inc()
+}
+
+// SetConditions reports the current conditions of the given instance.
+func SetConditions(key types.NamespacedName, conditions []metav1.Condition) {
+	instances.update(key, func(st *instanceState) {
+		st.conditions = append([]metav1.Condition{}, conditions...)
+	})
+}
+
+// SetNodeTopology reports the freshness of the NodeResourceTopology objects of the nodes served by the given instance.
+func SetNodeTopology(key types.NamespacedName, nodes []topologyexporterv1beta1.NodeTopologyStatus) {
+	instances.update(key, func(st *instanceState) {
+		st.nodes = append([]topologyexporterv1beta1.NodeTopologyStatus{}, nodes...)
+	})
+}
+
+// Forget drops the metrics of the given instance, which is gone.
+func Forget(key types.NamespacedName) {
+	instances.forget(key)
+}
+
+type instanceState struct {
+	conditions []metav1.Condition
+	nodes      []topologyexporterv1beta1.NodeTopologyStatus
+}
+
+// instanceCollector reports the state of the instances as of their last reconciliation.
+// The age of the NodeResourceTopology objects is computed when scraped, so it keeps growing between reconciliations.
+type instanceCollector struct {
+	conditionStatus   *prometheus.Desc
+	nodeTopologyStale *prometheus.Desc
+	nodeTopologyAge   *prometheus.Desc
+
+	lock   sync.Mutex
+	states map[types.NamespacedName]*instanceState
+}
+
+func (c *instanceCollector) update(key types.NamespacedName, updateFunc func(st *instanceState)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	st, ok := c.states[key]
+	if !ok {
+		st = &instanceState{}
+		c.states[key] = st
+	}
+	updateFunc(st)
+}
+
+func (c *instanceCollector) forget(key types.NamespacedName) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.states, key)
+}
+
+func (c *instanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.conditionStatus
+	ch <- c.nodeTopologyStale
+	ch <- c.nodeTopologyAge
+}
+
+func (c *instanceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := time.Now()
+	for key, st := range c.states {
+		for _, cond := range st.conditions {
+			ch <- prometheus.MustNewConstMetric(c.conditionStatus, prometheus.GaugeValue, boolToFloat(cond.Status == metav1.ConditionTrue), key.Namespace, key.Name, cond.Type)
+		}
+		for _, nts := range st.nodes {
+			ch <- prometheus.MustNewConstMetric(c.nodeTopologyStale, prometheus.GaugeValue, boolToFloat(!nts.Fresh), key.Namespace, key.Name, nts.NodeName)
+			if nts.LastUpdateTime != nil {
+				ch <- prometheus.MustNewConstMetric(c.nodeTopologyAge, prometheus.GaugeValue, now.Sub(nts.LastUpdateTime.Time).Seconds(), key.Namespace, key.Name, nts.NodeName)
+			}
+		}
+	}
+}
+
+func boolToFloat(val bool) float64 {
+	if val {
+		return 1.0
+	}
+	return 0.0
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+func TestInstanceMetrics(t *testing.T) {
+	key := types.NamespacedName{Namespace: "rte", Name: "pool-a"}
+	lastUpdate := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	SetConditions(key, []metav1.Condition{
+		{Type: "Available", Status: metav1.ConditionTrue},
+		{Type: "Degraded", Status: metav1.ConditionFalse},
+	})
+	SetNodeTopology(key, []topologyexporterv1beta1.NodeTopologyStatus{
+		{NodeName: "node-a", Fresh: true, LastUpdateTime: &lastUpdate},
+		{NodeName: "node-b", Reason: "TopologyMissing"},
+	})
+	defer Forget(key)
+
+	expected := `
+# HELP rte_operator_condition_status Status of the conditions of the ResourceTopologyExporter instances: 1 if true, 0 otherwise.
+# TYPE rte_operator_condition_status gauge
+rte_operator_condition_status{condition="Available",exporter_namespace="rte",name="pool-a"} 1
+rte_operator_condition_status{condition="Degraded",exporter_namespace="rte",name="pool-a"} 0
+# HELP rte_operator_node_topology_stale Whether the NodeResourceTopology object of the node is stale: 1 if stale, 0 otherwise.
+# TYPE rte_operator_node_topology_stale gauge
+rte_operator_node_topology_stale{exporter_namespace="rte",name="pool-a",node="node-a"} 0
+rte_operator_node_topology_stale{exporter_namespace="rte",name="pool-a",node="node-b"} 1
+`
+	if err := testutil.CollectAndCompare(instances, strings.NewReader(expected), "rte_operator_condition_status", "rte_operator_node_topology_stale"); err != nil {
+		t.Errorf("unexpected metrics: %v", err)
+	}
+	// only the nodes with a NodeResourceTopology object have an age
+	if count := testutil.CollectAndCount(instances, "rte_operator_node_topology_last_update_age_seconds"); count != 1 {
+		t.Errorf("unexpected age metrics count: %d", count)
+	}
+
+	Forget(key)
+	if count := testutil.CollectAndCount(instances); count != 0 {
+		t.Errorf("metrics left after forgetting the instance: %d", count)
+	}
+}
+
+func TestObjectWrite(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "DaemonSet"}
+	before := testutil.ToFloat64(objectWrites.WithLabelValues("apps", "v1", "DaemonSet", VerbApply))
+	ObjectWrite(gvk, VerbApply)
+	if got := testutil.ToFloat64(objectWrites.WithLabelValues("apps", "v1", "DaemonSet", VerbApply)); got != before+1 {
+		t.Errorf("expected %v writes got %v", before+1, got)
+	}
+}
@@ -41,8 +41,8 @@ func (em ExistingManifests) State(mf apimanifests.Manifests) []objectstate.Objec
 			Existing: em.Existing.Crd,
 			Error:    em.CrdError,
 			Desired:  mf.Crd.DeepCopy(),
-			Compare:  compare.Object,
-			Merge:    merge.MetadataForUpdate,
+			Compare:  compare.DesiredFields,
+			Merge:    merge.CRDForUpdate,
 		},
 	}
 }
@@ -17,11 +17,236 @@
 package compare
 
 import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+
 	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ignoredFields are never part of the desired state, or may be missing in the existing objects.
+var ignoredFields = []string{"apiVersion", "kind", "status"}
+
 func Object(existing, obj client.Object) (bool, error) {
 	return equality.Semantic.DeepEqual(existing, obj), nil
 }
+
+// DesiredFields tells if the existing object has all the fields set in the desired object, with the same values.
+// The fields set only in the existing object, like the ones defaulted by the API server, are ignored.
+// Lists must have the same length, so items added to lists are detected.
+// The fields cleared in the desired object can't be told apart from the defaulted ones, so they are not detected:
+// use OwnedFields for objects applied server-side.
+func DesiredFields(existing, desired client.Object) (bool, error) {
+	if existing == nil || desired == nil {
+		return existing == nil && desired == nil, nil
+	}
+	exData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(existing)
+	if err != nil {
+		return false, err
+	}
+	deData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return false, err
+	}
+	for _, field := range ignoredFields {
+		delete(exData, field)
+		delete(deData, field)
+	}
+	return isSubset(deData, exData), nil
+}
+
+// appliedMetadataFields are the metadata fields server-side apply tracks the ownership of.
+var appliedMetadataFields = []string{"labels", "annotations", "ownerReferences", "finalizers"}
+
+// OwnedFields returns a comparator telling if the fields the given field manager owns through server-side apply
+// in the existing object match the desired object. The fields other actors set, like the sidecars injected by
+// admission webhooks or the values defaulted by the API server, are ignored. Unlike DesiredFields, the owned fields
+// set in the existing object but no longer in the desired one, like a cleared node selector, are differences.
+// Objects the field manager never applied are always different.
+func OwnedFields(fieldManager string) func(existing, desired client.Object) (bool, error) {
+	return func(existing, desired client.Object) (bool, error) {
+		if existing == nil || desired == nil {
+			return existing == nil && desired == nil, nil
+		}
+		exData, found, err := ownedFields(existing, fieldManager)
+		if err != nil || !found {
+			return false, err
+		}
+		deData, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+		if err != nil {
+			return false, err
+		}
+		for _, field := range ignoredFields {
+			delete(exData, field)
+			delete(deData, field)
+		}
+		if meta, ok := deData["metadata"].(map[string]interface{}); ok {
+			applied := map[string]interface{}{}
+			for _, field := range appliedMetadataFields {
+				if val, ok := meta[field]; ok {
+					applied[field] = val
+				}
+			}
+			deData["metadata"] = applied
+		}
+		return isSubset(deData, exData) && isSubset(exData, deData), nil
+	}
+}
+
+// ownedFields returns the parts of the given object the given field manager set through server-side apply.
+func ownedFields(obj client.Object, fieldManager string) (map[string]interface{}, bool, error) {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, false, err
+		}
+		data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, false, err
+		}
+		owned, _ := extractFields(data, fields).(map[string]interface{})
+		return owned, true, nil
+	}
+	return nil, false, nil
+}
+
+// extractFields returns the parts of the given value listed in the given set, in the managedFields format:
+// "f:<name>" for the map fields, "k:<keys>", "v:<value>" and "i:<index>" for the list items.
+// Owning a map without any of its fields only means owning its presence, while lists without keyed items are atomic.
+func extractFields(value interface{}, fields map[string]interface{}) interface{} {
+	if !hasChildren(fields) {
+		if _, ok := value.(map[string]interface{}); ok {
+			return map[string]interface{}{}
+		}
+		return value
+	}
+	switch val := value.(type) {
+	case map[string]interface{}:
+		ret := map[string]interface{}{}
+		for key, sub := range fields {
+			if !strings.HasPrefix(key, "f:") {
+				continue
+			}
+			name := strings.TrimPrefix(key, "f:")
+			child, ok := val[name]
+			if !ok {
+				continue
+			}
+			subFields, _ := sub.(map[string]interface{})
+			ret[name] = extractFields(child, subFields)
+		}
+		return ret
+	case []interface{}:
+		ret := []interface{}{}
+		for idx, item := range val {
+			if subFields, ok := matchItem(fields, idx, item); ok {
+				ret = append(ret, extractFields(item, subFields))
+			}
+		}
+		return ret
+	}
+	return value
+}
+
+func hasChildren(fields map[string]interface{}) bool {
+	for key := range fields {
+		if key != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// matchItem finds in the given set the entry of the given list item, and returns its fields.
+func matchItem(fields map[string]interface{}, idx int, item interface{}) (map[string]interface{}, bool) {
+	for key, sub := range fields {
+		subFields, _ := sub.(map[string]interface{})
+		switch {
+		case strings.HasPrefix(key, "i:"):
+			if key == "i:"+strconv.Itoa(idx) {
+				return subFields, true
+			}
+		case strings.HasPrefix(key, "v:"):
+			if jsonEqual(strings.TrimPrefix(key, "v:"), item) {
+				return subFields, true
+			}
+		case strings.HasPrefix(key, "k:"):
+			if matchKeys(strings.TrimPrefix(key, "k:"), item) {
+				return subFields, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func matchKeys(rawKeys string, item interface{}) bool {
+	keys := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(rawKeys), &keys); err != nil {
+		return false
+	}
+	itemMap, ok := item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for name, keyVal := range keys {
+		raw, err := json.Marshal(keyVal)
+		if err != nil || !jsonEqual(string(raw), itemMap[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual tells if the given JSON encodes the given value. Comparing the encodings
+// makes the numbers decoded as float64 match the int64 ones of the unstructured objects.
+func jsonEqual(raw string, value interface{}) bool {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		return false
+	}
+	expected, err := json.Marshal(decoded)
+	if err != nil {
+		return false
+	}
+	got, err := json.Marshal(value)
+	return err == nil && string(expected) == string(got)
+}
+
+func isSubset(desired, existing interface{}) bool {
+	switch desVal := desired.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		exVal, _ := existing.(map[string]interface{})
+		for key, val := range desVal {
+			if !isSubset(val, exVal[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		if len(desVal) == 0 {
+			return true
+		}
+		exVal, ok := existing.([]interface{})
+		if !ok || len(exVal) != len(desVal) {
+			return false
+		}
+		for idx := range desVal {
+			if !isSubset(desVal[idx], exVal[idx]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(desired, existing)
+	}
+}
@@ -17,12 +17,31 @@
 package compare
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
 
+	"github.com/go-logr/logr"
+
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	apimanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/api"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+
+	"github.com/openshift-kni/rte-operator/pkg/apply"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate"
+	"github.com/openshift-kni/rte-operator/pkg/objectstate/merge"
 )
 
 type testCase struct {
@@ -77,3 +96,401 @@ func TestCompareObject(t *testing.T) {
 		})
 	}
 }
+
+func TestDesiredFields(t *testing.T) {
+	mf, err := rtemanifests.GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("cannot load the manifests: %v", err)
+	}
+
+	extraEnv := fromAPIServer(mf.DaemonSet).(*appsv1.DaemonSet)
+	cnt := &extraEnv.Spec.Template.Spec.Containers[0]
+	cnt.Env = append(cnt.Env, corev1.EnvVar{Name: "INJECTED", Value: "1"})
+
+	otherImage := fromAPIServer(mf.DaemonSet).(*appsv1.DaemonSet)
+	otherImage.Spec.Template.Spec.Containers[0].Image = "quay.io/example/not-the-exporter:latest"
+
+	extraLabels := fromAPIServer(mf.DaemonSet).(*appsv1.DaemonSet)
+	extraLabels.Labels = map[string]string{"example.com/team": "numa"}
+
+	noRules := fromAPIServer(mf.Role).(*rbacv1.Role)
+	noRules.Rules = nil
+
+	testCases := []testCase{
+		{
+			description:   "both nil",
+			expectedEqual: true,
+		},
+		{
+			description:   "nil vs non-nil",
+			objB:          &corev1.Namespace{},
+			expectedEqual: false,
+		},
+		{
+			description:   "defaulted daemonset",
+			objA:          fromAPIServer(mf.DaemonSet),
+			objB:          mf.DaemonSet,
+			expectedEqual: true,
+		},
+		{
+			description:   "daemonset with labels added",
+			objA:          extraLabels,
+			objB:          mf.DaemonSet,
+			expectedEqual: true,
+		},
+		{
+			description:   "daemonset with env var added",
+			objA:          extraEnv,
+			objB:          mf.DaemonSet,
+			expectedEqual: false,
+		},
+		{
+			description:   "daemonset with image changed",
+			objA:          otherImage,
+			objB:          mf.DaemonSet,
+			expectedEqual: false,
+		},
+		{
+			description:   "role with rules removed",
+			objA:          noRules,
+			objB:          mf.Role,
+			expectedEqual: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			res, err := DesiredFields(tc.objA, tc.objB)
+			if res != tc.expectedEqual {
+				t.Errorf("expected equal=%t actual=%t", tc.expectedEqual, res)
+			}
+			if err != tc.expectedError {
+				t.Errorf("expected err=%v actual=%v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestOwnedFields(t *testing.T) {
+	mf, err := rtemanifests.GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("cannot load the manifests: %v", err)
+	}
+
+	injected := fromAPIServer(mf.DaemonSet).(*appsv1.DaemonSet)
+	podSpec := &injected.Spec.Template.Spec
+	podSpec.Containers = append(podSpec.Containers, corev1.Container{Name: "injected-sidecar", Image: "quay.io/example/sidecar:latest"})
+	injected.Labels = map[string]string{"example.com/team": "numa"}
+
+	// the ownership of the changed field moves to the other field manager
+	otherImage := fromAPIServer(mf.DaemonSet).(*appsv1.DaemonSet)
+	otherImage.Spec.Template.Spec.Containers[0].Image = "quay.io/example/not-the-exporter:latest"
+	changed := mf.DaemonSet.DeepCopy()
+	changed.Spec.Template.Spec.Containers[0].Image = ""
+	otherImage.ManagedFields[0].FieldsV1 = appliedFields(changed)
+
+	placed := mf.DaemonSet.DeepCopy()
+	placedSpec := &placed.Spec.Template.Spec
+	placedSpec.NodeSelector = map[string]string{"node-role.kubernetes.io/worker": ""}
+	placedSpec.Tolerations = []corev1.Toleration{{Key: "node-role.kubernetes.io/master", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}}
+	placedSpec.Affinity = &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "feature.node.kubernetes.io/cpu-cpuid.AVX512F", Operator: corev1.NodeSelectorOpExists},
+						},
+					},
+				},
+			},
+		},
+	}
+	placedSpec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "pull-secret"}}
+
+	clearedFields := map[string]func(podSpec *corev1.PodSpec){
+		"node selector":      func(podSpec *corev1.PodSpec) { podSpec.NodeSelector = nil },
+		"tolerations":        func(podSpec *corev1.PodSpec) { podSpec.Tolerations = nil },
+		"affinity":           func(podSpec *corev1.PodSpec) { podSpec.Affinity = nil },
+		"image pull secrets": func(podSpec *corev1.PodSpec) { podSpec.ImagePullSecrets = nil },
+	}
+
+	neverApplied := fromAPIServer(mf.DaemonSet).(*appsv1.DaemonSet)
+	neverApplied.ManagedFields[0].Operation = metav1.ManagedFieldsOperationUpdate
+
+	testCases := []testCase{
+		{
+			description:   "both nil",
+			expectedEqual: true,
+		},
+		{
+			description:   "defaulted daemonset",
+			objA:          fromAPIServer(mf.DaemonSet),
+			objB:          mf.DaemonSet,
+			expectedEqual: true,
+		},
+		{
+			description:   "daemonset with sidecar and labels injected",
+			objA:          injected,
+			objB:          mf.DaemonSet,
+			expectedEqual: true,
+		},
+		{
+			description:   "daemonset with image changed by someone else",
+			objA:          otherImage,
+			objB:          mf.DaemonSet,
+			expectedEqual: false,
+		},
+		{
+			description:   "daemonset never applied",
+			objA:          neverApplied,
+			objB:          mf.DaemonSet,
+			expectedEqual: false,
+		},
+		{
+			description:   "daemonset with placement",
+			objA:          fromAPIServer(placed),
+			objB:          placed,
+			expectedEqual: true,
+		},
+	}
+	for field, clear := range clearedFields {
+		desired := placed.DeepCopy()
+		clear(&desired.Spec.Template.Spec)
+		testCases = append(testCases, testCase{
+			description:   "daemonset with " + field + " cleared",
+			objA:          fromAPIServer(placed),
+			objB:          desired,
+			expectedEqual: false,
+		})
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			res, err := OwnedFields(apply.FieldManager)(tc.objA, tc.objB)
+			if res != tc.expectedEqual {
+				t.Errorf("expected equal=%t actual=%t", tc.expectedEqual, res)
+			}
+			if err != tc.expectedError {
+				t.Errorf("expected err=%v actual=%v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestSteadyStateNoWrites(t *testing.T) {
+	apiMf, err := apimanifests.GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("cannot load the API manifests: %v", err)
+	}
+	mf, err := rtemanifests.GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("cannot load the RTE manifests: %v", err)
+	}
+	mf = mf.Update(rtemanifests.UpdateOptions{
+		ConfigData: "resources:\n  reservedCpus: \"0\"\n",
+		Namespace:  "rte-test",
+	})
+
+	cli := &writeCountingClient{}
+	for _, obj := range append(apiMf.ToObjects(), mf.ToObjects()...) {
+		objState := objectstate.ObjectState{
+			Existing: fromAPIServer(obj),
+			Desired:  obj.DeepCopyObject().(client.Object),
+			Compare:  DesiredFields,
+			Merge:    merge.ObjectForUpdate,
+		}
+		switch obj.(type) {
+		case *appsv1.DaemonSet:
+			objState.Compare = OwnedFields(apply.FieldManager)
+			objState.Merge = merge.DaemonSetForApply
+			objState.Mode = objectstate.ApplyModeServerSide
+		case *corev1.ServiceAccount:
+			objState.Merge = merge.ServiceAccountForUpdate
+		case *corev1.ConfigMap:
+			objState.Merge = merge.ConfigMapForUpdate
+		case *rbacv1.Role:
+			objState.Merge = merge.RoleForUpdate
+		case *rbacv1.RoleBinding:
+			objState.Merge = merge.RoleBindingForUpdate
+		case *apiextensionsv1.CustomResourceDefinition:
+			objState.Merge = merge.CRDForUpdate
+		}
+		if _, err := apply.ApplyObject(context.TODO(), logr.Discard(), cli, apply.Events{}, objState); err != nil {
+			t.Fatalf("cannot apply %s: %v", obj.GetName(), err)
+		}
+	}
+	if cli.writes != 0 {
+		t.Errorf("expected no writes in steady state, got %d", cli.writes)
+	}
+}
+
+// fromAPIServer returns a copy of the given object as the API server would return it after creation.
+func fromAPIServer(obj client.Object) client.Object {
+	ret := obj.DeepCopyObject().(client.Object)
+	// the typed client does not fill the type metadata
+	ret.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	ret.SetUID(types.UID("f2e8c6a4-2c5e-4a4b-9c1e-0d6b3c1f7d52"))
+	ret.SetResourceVersion("4242")
+	ret.SetGeneration(1)
+	ret.SetCreationTimestamp(metav1.Now())
+	ret.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    apply.FieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1:   appliedFields(obj),
+		},
+	})
+
+	switch typedObj := ret.(type) {
+	case *appsv1.DaemonSet:
+		defaultDaemonSet(typedObj)
+	case *corev1.ServiceAccount:
+		typedObj.Secrets = append(typedObj.Secrets, corev1.ObjectReference{Name: typedObj.Name + "-token-xyz12"})
+	case *apiextensionsv1.CustomResourceDefinition:
+		if typedObj.Spec.Conversion == nil {
+			typedObj.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}
+		}
+		typedObj.Status.StoredVersions = []string{"v1alpha1"}
+	}
+	return ret
+}
+
+// appliedFields returns the fields the API server records as owned by the manager applying the given object.
+func appliedFields(obj client.Object) *metav1.FieldsV1 {
+	data, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		panic(err)
+	}
+	delete(data, "apiVersion")
+	delete(data, "kind")
+	delete(data, "status")
+	meta := data["metadata"].(map[string]interface{})
+	data["metadata"] = map[string]interface{}{"labels": meta["labels"], "annotations": meta["annotations"]}
+	if meta["labels"] == nil && meta["annotations"] == nil {
+		delete(data, "metadata")
+	}
+	raw, err := json.Marshal(fieldSet(data))
+	if err != nil {
+		panic(err)
+	}
+	return &metav1.FieldsV1{Raw: raw}
+}
+
+// fieldSet mimics the API server: the lists of named items, or of mounts, are keyed, the other lists are atomic.
+func fieldSet(value interface{}) map[string]interface{} {
+	ret := map[string]interface{}{}
+	switch val := value.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			if child == nil {
+				continue
+			}
+			ret["f:"+key] = fieldSet(child)
+		}
+	case []interface{}:
+		for _, item := range val {
+			itemMap, ok := item.(map[string]interface{})
+			if !ok {
+				return ret
+			}
+			keyName := "name"
+			if _, ok := itemMap["mountPath"]; ok {
+				keyName = "mountPath"
+			}
+			if _, ok := itemMap[keyName]; !ok {
+				return ret
+			}
+			key, _ := json.Marshal(map[string]interface{}{keyName: itemMap[keyName]})
+			sub := fieldSet(item)
+			sub["."] = map[string]interface{}{}
+			ret["k:"+string(key)] = sub
+		}
+	}
+	return ret
+}
+
+func defaultDaemonSet(ds *appsv1.DaemonSet) {
+	revisionHistoryLimit := int32(10)
+	terminationGracePeriodSeconds := int64(30)
+	maxUnavailable := intstr.FromInt(1)
+	maxSurge := intstr.FromInt(0)
+	defaultMode := int32(0644)
+
+	if ds.Spec.RevisionHistoryLimit == nil {
+		ds.Spec.RevisionHistoryLimit = &revisionHistoryLimit
+	}
+	if ds.Spec.UpdateStrategy.Type == "" {
+		ds.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{
+			Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+				MaxUnavailable: &maxUnavailable,
+				MaxSurge:       &maxSurge,
+			},
+		}
+	}
+
+	podSpec := &ds.Spec.Template.Spec
+	if podSpec.RestartPolicy == "" {
+		podSpec.RestartPolicy = corev1.RestartPolicyAlways
+	}
+	if podSpec.TerminationGracePeriodSeconds == nil {
+		podSpec.TerminationGracePeriodSeconds = &terminationGracePeriodSeconds
+	}
+	if podSpec.DNSPolicy == "" {
+		podSpec.DNSPolicy = corev1.DNSClusterFirst
+	}
+	if podSpec.SchedulerName == "" {
+		podSpec.SchedulerName = corev1.DefaultSchedulerName
+	}
+	if podSpec.SecurityContext == nil {
+		podSpec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	for idx := range podSpec.Containers {
+		cnt := &podSpec.Containers[idx]
+		if cnt.ImagePullPolicy == "" {
+			cnt.ImagePullPolicy = corev1.PullIfNotPresent
+		}
+		if cnt.TerminationMessagePath == "" {
+			cnt.TerminationMessagePath = corev1.TerminationMessagePathDefault
+		}
+		if cnt.TerminationMessagePolicy == "" {
+			cnt.TerminationMessagePolicy = corev1.TerminationMessageReadFile
+		}
+	}
+	for idx := range podSpec.Volumes {
+		if cm := podSpec.Volumes[idx].ConfigMap; cm != nil && cm.DefaultMode == nil {
+			cm.DefaultMode = &defaultMode
+		}
+	}
+
+	ds.Status = appsv1.DaemonSetStatus{
+		CurrentNumberScheduled: 3,
+		DesiredNumberScheduled: 3,
+		NumberReady:            3,
+		UpdatedNumberScheduled: 3,
+		ObservedGeneration:     1,
+	}
+}
+
+// writeCountingClient counts the writes. Reads panic, as ApplyObject must only use the given state.
+type writeCountingClient struct {
+	client.Client
+	writes int
+}
+
+func (c *writeCountingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	c.writes++
+	return nil
+}
+
+func (c *writeCountingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.writes++
+	return nil
+}
+
+func (c *writeCountingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.writes++
+	return nil
+}
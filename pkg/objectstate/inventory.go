@@ -0,0 +1,85 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package objectstate
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InventoryLabel marks the objects managed on behalf of an owner, identified by its UID,
+// so the ones no longer desired can be found and pruned.
+const InventoryLabel = "topologyexporter.openshift-kni.io/owner-uid"
+
+// SetInventoryLabel adds the given object to the inventory of the given owner.
+func SetInventoryLabel(obj client.Object, ownerUID types.UID) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[InventoryLabel] = string(ownerUID)
+	obj.SetLabels(labels)
+}
+
+// Prunable returns the objects in the inventory of the given owner which are not among the desired ones.
+// The lists tell which kinds of objects the inventory includes.
+func Prunable(ctx context.Context, cli client.Client, ownerUID types.UID, lists []client.ObjectList, desired []client.Object) ([]client.Object, error) {
+	inventory := []client.Object{}
+	for _, list := range lists {
+		if err := cli.List(ctx, list, client.MatchingLabels{InventoryLabel: string(ownerUID)}); err != nil {
+			return nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				return nil, fmt.Errorf("unexpected item type %T in inventory list", item)
+			}
+			inventory = append(inventory, obj)
+		}
+	}
+	return Unwanted(inventory, desired), nil
+}
+
+// Unwanted returns the existing objects which are not among the desired ones.
+func Unwanted(existing, desired []client.Object) []client.Object {
+	wanted := make(map[string]bool)
+	for _, obj := range desired {
+		wanted[inventoryKey(obj)] = true
+	}
+	ret := []client.Object{}
+	for _, obj := range existing {
+		if !wanted[inventoryKey(obj)] {
+			ret = append(ret, obj)
+		}
+	}
+	return ret
+}
+
+// inventoryKey identifies the objects by type, because the typed objects read from the
+// cluster may lack their GVK.
+func inventoryKey(obj client.Object) string {
+	return fmt.Sprintf("%T %s", obj, client.ObjectKeyFromObject(obj))
+}
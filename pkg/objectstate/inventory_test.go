@@ -0,0 +1,82 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package objectstate
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestSetInventoryLabel(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{"app": "rte"},
+		},
+	}
+	SetInventoryLabel(cm, types.UID("1234"))
+	expected := map[string]string{"app": "rte", InventoryLabel: "1234"}
+	if !reflect.DeepEqual(cm.Labels, expected) {
+		t.Errorf("expected labels %v got %v", expected, cm.Labels)
+	}
+
+	ds := &appsv1.DaemonSet{}
+	SetInventoryLabel(ds, types.UID("5678"))
+	if ds.Labels[InventoryLabel] != "5678" {
+		t.Errorf("missing inventory label: %v", ds.Labels)
+	}
+}
+
+func TestUnwanted(t *testing.T) {
+	objMeta := func(namespace, name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: namespace, Name: name}
+	}
+
+	existing := []client.Object{
+		&corev1.ConfigMap{ObjectMeta: objMeta("rte", "rte-config")},
+		&corev1.ServiceAccount{ObjectMeta: objMeta("rte", "rte")},
+		&appsv1.DaemonSet{ObjectMeta: objMeta("rte", "resource-topology-exporter")},
+		&appsv1.DaemonSet{ObjectMeta: objMeta("old-ns", "resource-topology-exporter")},
+	}
+	desired := []client.Object{
+		// same name, different kind: must not save the ConfigMap
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: objMeta("rte", "rte-config"),
+		},
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: objMeta("rte", "rte"),
+		},
+		&appsv1.DaemonSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+			ObjectMeta: objMeta("rte", "resource-topology-exporter"),
+		},
+	}
+
+	got := Unwanted(existing, desired)
+	expected := []client.Object{existing[0], existing[3]}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected unwanted %v got %v", expected, got)
+	}
+}
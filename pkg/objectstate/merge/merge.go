@@ -19,14 +19,28 @@ package merge
 import (
 	"fmt"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// RestartedAtAnnotation is set on the pod template by `kubectl rollout restart`.
+	RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+	// KeepInjectedAnnotation, set to "true" on a DaemonSet, keeps the containers and volumes
+	// other actors, like admission webhooks, added to its pod template.
+	KeepInjectedAnnotation = "topologyexporter.openshift-kni.io/keep-injected"
+)
+
 var (
 	ErrWrongObjectType    = fmt.Errorf("given object does not match the merger")
 	ErrMismatchingObjects = fmt.Errorf("given objects have mismatching types")
+	ErrImmutableField     = fmt.Errorf("given objects differ in immutable fields")
 )
 
 func ServiceAccountForUpdate(current, updated client.Object) (client.Object, error) {
@@ -42,6 +56,115 @@ func ServiceAccountForUpdate(current, updated client.Object) (client.Object, err
 	return MetadataForUpdate(current, updated)
 }
 
+// DaemonSetForApply merges the DaemonSets applied server-side, which leaves alone the fields
+// other field managers own, like the restartedAt annotation or the containers they added.
+// So only the injected containers and volumes recorded as owned by the operator, because
+// they were added while it applied the DaemonSet, need to be kept under KeepInjectedAnnotation.
+// The existing metadata is not copied, as applying it would take it over.
+func DaemonSetForApply(current, updated client.Object) (client.Object, error) {
+	curDS, ok := current.(*appsv1.DaemonSet)
+	if !ok {
+		return updated, ErrWrongObjectType
+	}
+	updDS, ok := updated.(*appsv1.DaemonSet)
+	if !ok {
+		return updated, ErrMismatchingObjects
+	}
+	keepInjected(curDS, updDS)
+	return updated, nil
+}
+
+func keepInjected(curDS, updDS *appsv1.DaemonSet) {
+	if curDS.Annotations[KeepInjectedAnnotation] != "true" {
+		return
+	}
+	curPod, updPod := &curDS.Spec.Template.Spec, &updDS.Spec.Template.Spec
+	updPod.InitContainers = mergeContainers(curPod.InitContainers, updPod.InitContainers)
+	updPod.Containers = mergeContainers(curPod.Containers, updPod.Containers)
+	updPod.Volumes = mergeVolumes(curPod.Volumes, updPod.Volumes)
+}
+
+func ConfigMapForUpdate(current, updated client.Object) (client.Object, error) {
+	curCM, ok := current.(*corev1.ConfigMap)
+	if !ok {
+		return updated, ErrWrongObjectType
+	}
+	updCM, ok := updated.(*corev1.ConfigMap)
+	if !ok {
+		return updated, ErrMismatchingObjects
+	}
+
+	// keep the keys added by the user, while ours always win
+	for key, val := range curCM.Data {
+		if _, ok := updCM.Data[key]; ok {
+			continue
+		}
+		if updCM.Data == nil {
+			updCM.Data = map[string]string{}
+		}
+		updCM.Data[key] = val
+	}
+	for key, val := range curCM.BinaryData {
+		if _, ok := updCM.BinaryData[key]; ok {
+			continue
+		}
+		if updCM.BinaryData == nil {
+			updCM.BinaryData = map[string][]byte{}
+		}
+		updCM.BinaryData[key] = val
+	}
+	return MetadataForUpdate(current, updated)
+}
+
+func RoleForUpdate(current, updated client.Object) (client.Object, error) {
+	if _, ok := current.(*rbacv1.Role); !ok {
+		return updated, ErrWrongObjectType
+	}
+	if _, ok := updated.(*rbacv1.Role); !ok {
+		return updated, ErrMismatchingObjects
+	}
+	// the rules grant permissions, so only ours are allowed
+	return MetadataForUpdate(current, updated)
+}
+
+func RoleBindingForUpdate(current, updated client.Object) (client.Object, error) {
+	curRB, ok := current.(*rbacv1.RoleBinding)
+	if !ok {
+		return updated, ErrWrongObjectType
+	}
+	updRB, ok := updated.(*rbacv1.RoleBinding)
+	if !ok {
+		return updated, ErrMismatchingObjects
+	}
+	if !equality.Semantic.DeepEqual(curRB.RoleRef, updRB.RoleRef) {
+		return updated, ErrImmutableField
+	}
+	// the subjects are granted permissions, so only ours are allowed
+	return MetadataForUpdate(current, updated)
+}
+
+func CRDForUpdate(current, updated client.Object) (client.Object, error) {
+	curCRD, ok := current.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		return updated, ErrWrongObjectType
+	}
+	updCRD, ok := updated.(*apiextensionsv1.CustomResourceDefinition)
+	if !ok {
+		return updated, ErrMismatchingObjects
+	}
+
+	updCRD.Status = curCRD.Status
+	// the conversion may be configured, and the CA bundle injected, by other tooling
+	if updCRD.Spec.Conversion == nil {
+		updCRD.Spec.Conversion = curCRD.Spec.Conversion
+	} else if caBundle := webhookCABundle(curCRD.Spec.Conversion); caBundle != nil {
+		if clientConfig := webhookClientConfig(updCRD.Spec.Conversion); clientConfig != nil && len(clientConfig.CABundle) == 0 {
+			clientConfig.CABundle = caBundle
+		}
+	}
+	return MetadataForUpdate(current, updated)
+}
+
 func ObjectForUpdate(current, updated client.Object) (client.Object, error) {
 	return MetadataForUpdate(current, updated)
 }
@@ -96,3 +219,46 @@ func Labels(current, updated client.Object) client.Object {
 	}
 	return updated
 }
+
+// mergeContainers appends to the updated containers the current ones with different names.
+func mergeContainers(current, updated []corev1.Container) []corev1.Container {
+	names := make(map[string]bool)
+	for _, cnt := range updated {
+		names[cnt.Name] = true
+	}
+	for _, cnt := range current {
+		if !names[cnt.Name] {
+			updated = append(updated, cnt)
+		}
+	}
+	return updated
+}
+
+// mergeVolumes appends to the updated volumes the current ones with different names.
+func mergeVolumes(current, updated []corev1.Volume) []corev1.Volume {
+	names := make(map[string]bool)
+	for _, vol := range updated {
+		names[vol.Name] = true
+	}
+	for _, vol := range current {
+		if !names[vol.Name] {
+			updated = append(updated, vol)
+		}
+	}
+	return updated
+}
+
+func webhookClientConfig(conv *apiextensionsv1.CustomResourceConversion) *apiextensionsv1.WebhookClientConfig {
+	if conv == nil || conv.Webhook == nil {
+		return nil
+	}
+	return conv.Webhook.ClientConfig
+}
+
+func webhookCABundle(conv *apiextensionsv1.CustomResourceConversion) []byte {
+	clientConfig := webhookClientConfig(conv)
+	if clientConfig == nil {
+		return nil
+	}
+	return clientConfig.CABundle
+}
@@ -0,0 +1,271 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package merge
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type testCase struct {
+	description   string
+	current       client.Object
+	updated       client.Object
+	expected      client.Object
+	expectedError error
+}
+
+func runTestCases(t *testing.T, merger func(current, updated client.Object) (client.Object, error), testCases []testCase) {
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			got, err := merger(tc.current, tc.updated)
+			if err != tc.expectedError {
+				t.Fatalf("expected err=%v actual=%v", tc.expectedError, err)
+			}
+			if tc.expected != nil && !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("merge mismatch:\nexpected %+v\ngot      %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDaemonSetForApply(t *testing.T) {
+	makeDS := func(annotations, podAnnotations map[string]string, containers []string) *appsv1.DaemonSet {
+		ds := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "rte",
+				Annotations: annotations,
+			},
+		}
+		ds.Spec.Template.Annotations = podAnnotations
+		for _, name := range containers {
+			ds.Spec.Template.Spec.Containers = append(ds.Spec.Template.Spec.Containers, corev1.Container{Name: name})
+		}
+		return ds
+	}
+	keep := map[string]string{KeepInjectedAnnotation: "true"}
+	restarted := map[string]string{RestartedAtAnnotation: "2021-10-01T10:00:00Z"}
+
+	runTestCases(t, DaemonSetForApply, []testCase{
+		{
+			description:   "wrong current type",
+			current:       &corev1.ConfigMap{},
+			updated:       &appsv1.DaemonSet{},
+			expectedError: ErrWrongObjectType,
+		},
+		{
+			description:   "mismatching types",
+			current:       &appsv1.DaemonSet{},
+			updated:       &corev1.ConfigMap{},
+			expectedError: ErrMismatchingObjects,
+		},
+		{
+			description: "leave restartedAt to its owner",
+			current:     makeDS(nil, restarted, []string{"rte"}),
+			updated:     makeDS(nil, nil, []string{"rte"}),
+			expected:    makeDS(nil, nil, []string{"rte"}),
+		},
+		{
+			description: "leave injected containers to their owner by default",
+			current:     makeDS(nil, nil, []string{"rte", "sidecar"}),
+			updated:     makeDS(nil, nil, []string{"rte"}),
+			expected:    makeDS(nil, nil, []string{"rte"}),
+		},
+		{
+			description: "keep injected containers on request, without taking over the metadata",
+			current:     makeDS(keep, nil, []string{"rte", "sidecar"}),
+			updated:     makeDS(nil, nil, []string{"rte"}),
+			expected:    makeDS(nil, nil, []string{"rte", "sidecar"}),
+		},
+	})
+}
+
+func TestConfigMapForUpdate(t *testing.T) {
+	makeCM := func(data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rte-config"},
+			Data:       data,
+		}
+	}
+
+	runTestCases(t, ConfigMapForUpdate, []testCase{
+		{
+			description:   "wrong current type",
+			current:       &corev1.ServiceAccount{},
+			updated:       &corev1.ConfigMap{},
+			expectedError: ErrWrongObjectType,
+		},
+		{
+			description:   "mismatching types",
+			current:       &corev1.ConfigMap{},
+			updated:       &corev1.ServiceAccount{},
+			expectedError: ErrMismatchingObjects,
+		},
+		{
+			description: "keep user keys, ours win",
+			current:     makeCM(map[string]string{"config.yaml": "old", "notes.txt": "hello"}),
+			updated:     makeCM(map[string]string{"config.yaml": "new"}),
+			expected:    makeCM(map[string]string{"config.yaml": "new", "notes.txt": "hello"}),
+		},
+		{
+			description: "no desired data",
+			current:     makeCM(map[string]string{"notes.txt": "hello"}),
+			updated:     makeCM(nil),
+			expected:    makeCM(map[string]string{"notes.txt": "hello"}),
+		},
+	})
+}
+
+func TestRoleForUpdate(t *testing.T) {
+	makeRole := func(verbs ...string) *rbacv1.Role {
+		return &rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "rte"},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: verbs},
+			},
+		}
+	}
+
+	runTestCases(t, RoleForUpdate, []testCase{
+		{
+			description:   "wrong current type",
+			current:       &rbacv1.RoleBinding{},
+			updated:       &rbacv1.Role{},
+			expectedError: ErrWrongObjectType,
+		},
+		{
+			description:   "mismatching types",
+			current:       &rbacv1.Role{},
+			updated:       &rbacv1.RoleBinding{},
+			expectedError: ErrMismatchingObjects,
+		},
+		{
+			description: "rules are ours",
+			current:     makeRole("get", "list", "delete"),
+			updated:     makeRole("get"),
+			expected:    makeRole("get"),
+		},
+	})
+}
+
+func TestRoleBindingForUpdate(t *testing.T) {
+	makeRB := func(roleName string, subjects ...string) *rbacv1.RoleBinding {
+		rb := &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "rte"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: roleName},
+		}
+		for _, name := range subjects {
+			rb.Subjects = append(rb.Subjects, rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Name: name})
+		}
+		return rb
+	}
+
+	runTestCases(t, RoleBindingForUpdate, []testCase{
+		{
+			description:   "wrong current type",
+			current:       &rbacv1.Role{},
+			updated:       &rbacv1.RoleBinding{},
+			expectedError: ErrWrongObjectType,
+		},
+		{
+			description:   "mismatching types",
+			current:       &rbacv1.RoleBinding{},
+			updated:       &rbacv1.Role{},
+			expectedError: ErrMismatchingObjects,
+		},
+		{
+			description:   "changed role ref",
+			current:       makeRB("rte", "rte"),
+			updated:       makeRB("rte-other", "rte"),
+			expectedError: ErrImmutableField,
+		},
+		{
+			description: "subjects are ours",
+			current:     makeRB("rte", "rte", "intruder"),
+			updated:     makeRB("rte", "rte"),
+			expected:    makeRB("rte", "rte"),
+		},
+	})
+}
+
+func TestCRDForUpdate(t *testing.T) {
+	makeCRD := func(conv *apiextensionsv1.CustomResourceConversion, storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "noderesourcetopologies.topology.node.k8s.io"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Conversion: conv,
+			},
+			Status: apiextensionsv1.CustomResourceDefinitionStatus{
+				StoredVersions: storedVersions,
+			},
+		}
+	}
+	webhookConv := func(caBundle string) *apiextensionsv1.CustomResourceConversion {
+		conv := &apiextensionsv1.CustomResourceConversion{
+			Strategy: apiextensionsv1.WebhookConverter,
+			Webhook: &apiextensionsv1.WebhookConversion{
+				ClientConfig:             &apiextensionsv1.WebhookClientConfig{},
+				ConversionReviewVersions: []string{"v1"},
+			},
+		}
+		if caBundle != "" {
+			conv.Webhook.ClientConfig.CABundle = []byte(caBundle)
+		}
+		return conv
+	}
+
+	runTestCases(t, CRDForUpdate, []testCase{
+		{
+			description:   "wrong current type",
+			current:       &corev1.ConfigMap{},
+			updated:       &apiextensionsv1.CustomResourceDefinition{},
+			expectedError: ErrWrongObjectType,
+		},
+		{
+			description:   "mismatching types",
+			current:       &apiextensionsv1.CustomResourceDefinition{},
+			updated:       &corev1.ConfigMap{},
+			expectedError: ErrMismatchingObjects,
+		},
+		{
+			description: "preserve status and conversion",
+			current:     makeCRD(webhookConv("CA"), "v1alpha1"),
+			updated:     makeCRD(nil),
+			expected:    makeCRD(webhookConv("CA"), "v1alpha1"),
+		},
+		{
+			description: "preserve injected CA bundle",
+			current:     makeCRD(webhookConv("CA")),
+			updated:     makeCRD(webhookConv("")),
+			expected:    makeCRD(webhookConv("CA")),
+		},
+		{
+			description: "desired CA bundle wins",
+			current:     makeCRD(webhookConv("CA")),
+			updated:     makeCRD(webhookConv("NEWCA")),
+			expected:    makeCRD(webhookConv("NEWCA")),
+		},
+	})
+}
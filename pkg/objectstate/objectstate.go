@@ -22,12 +22,26 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ApplyMode tells how the desired state of an object is pushed to the cluster.
+type ApplyMode int
+
+const (
+	// ApplyModeUpdate merges the desired object into the existing one, and updates it if they differ.
+	ApplyModeUpdate ApplyMode = iota
+	// ApplyModeServerSide uses server-side apply, so the operator owns only the fields it sets
+	// and leaves alone the fields set by other actors.
+	ApplyModeServerSide
+)
+
 type ObjectState struct {
 	Existing client.Object
 	Desired  client.Object
 	Error    error
 	Compare  func(existing, desired client.Object) (bool, error)
 	Merge    func(existing, desired client.Object) (client.Object, error)
+	// Mode defaults to ApplyModeUpdate. With ApplyModeServerSide, Merge must only add to the desired object
+	// the existing fields the operator should keep owning, since everything it applies is taken over.
+	Mode ApplyMode
 }
 
 func (obst ObjectState) IsNotFoundError() bool {
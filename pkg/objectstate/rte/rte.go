@@ -18,6 +18,11 @@ package rte
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -27,13 +32,43 @@ import (
 	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	topologyexporterv1alpha1 "github.com/openshift-kni/rte-operator/api/v1alpha1"
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
 
+	"github.com/openshift-kni/rte-operator/pkg/apply"
 	"github.com/openshift-kni/rte-operator/pkg/objectstate"
 	"github.com/openshift-kni/rte-operator/pkg/objectstate/compare"
 	"github.com/openshift-kni/rte-operator/pkg/objectstate/merge"
 )
 
+const (
+	// DefaultInstanceName is the name of the ResourceTopologyExporter instance which owns
+	// the objects named exactly as in the upstream manifests. This was the only instance
+	// name allowed by earlier versions of the operator, so this keeps upgrades seamless.
+	DefaultInstanceName = "resourcetopologyexporter"
+
+	// ConfigHashAnnotation is set on the exporter pods to roll them out when the configuration changes,
+	// because the exporter reads its configuration only at startup.
+	ConfigHashAnnotation = "topologyexporter.openshift-kni.io/config-hash"
+
+	// ExporterContainerName is the name of the exporter container in the DaemonSet manifest.
+	// Other containers, like the minion or the injected sidecars, may come before it.
+	ExporterContainerName = "resource-topology-exporter-container"
+)
+
+const (
+	// kubeletStateVolumePrefix names the volumes of the kubelet state dirs
+	kubeletStateVolumePrefix = "kubelet-state-"
+	// hostPathPrefix marks the paths in the exporter container mounted from the host
+	hostPathPrefix = "/host-"
+)
+
+const (
+	flagSleepInterval         = "--sleep-interval"
+	flagTopologyManagerPolicy = "--topology-manager-policy"
+	flagKubeletStateDir       = "--kubelet-state-dir"
+	flagReferenceContainer    = "--reference-container"
+)
+
 type ExistingManifests struct {
 	Existing            rtemanifests.Manifests
 	ServiceAccountError error
@@ -51,7 +86,7 @@ func (em ExistingManifests) State(mf rtemanifests.Manifests) []objectstate.Objec
 				Existing: em.Existing.ServiceAccount,
 				Error:    em.ServiceAccountError,
 				Desired:  mf.ServiceAccount.DeepCopy(),
-				Compare:  compare.Object,
+				Compare:  compare.DesiredFields,
 				Merge:    merge.ServiceAccountForUpdate,
 			},
 		)
@@ -62,8 +97,8 @@ func (em ExistingManifests) State(mf rtemanifests.Manifests) []objectstate.Objec
 				Existing: em.Existing.ConfigMap,
 				Error:    em.ConfigMapError,
 				Desired:  mf.ConfigMap.DeepCopy(),
-				Compare:  compare.Object,
-				Merge:    merge.ObjectForUpdate,
+				Compare:  compare.DesiredFields,
+				Merge:    merge.ConfigMapForUpdate,
 			},
 		)
 	}
@@ -72,26 +107,39 @@ func (em ExistingManifests) State(mf rtemanifests.Manifests) []objectstate.Objec
 			Existing: em.Existing.Role,
 			Error:    em.RoleError,
 			Desired:  mf.Role.DeepCopy(),
-			Compare:  compare.Object,
-			Merge:    merge.ObjectForUpdate,
+			Compare:  compare.DesiredFields,
+			Merge:    merge.RoleForUpdate,
 		},
 		objectstate.ObjectState{
 			Existing: em.Existing.RoleBinding,
 			Error:    em.RoleBindingError,
 			Desired:  mf.RoleBinding.DeepCopy(),
-			Compare:  compare.Object,
-			Merge:    merge.ObjectForUpdate,
+			Compare:  compare.DesiredFields,
+			Merge:    merge.RoleBindingForUpdate,
 		},
 		objectstate.ObjectState{
 			Existing: em.Existing.DaemonSet,
 			Error:    em.DaemonSetError,
 			Desired:  mf.DaemonSet.DeepCopy(),
-			Compare:  compare.Object,
-			Merge:    merge.ObjectForUpdate,
+			Compare:  compare.OwnedFields(apply.FieldManager),
+			Merge:    merge.DaemonSetForApply,
+			// admission controllers and other tools commonly add labels, annotations or even containers
+			Mode: objectstate.ApplyModeServerSide,
 		},
 	)
 }
 
+// InventoryLists returns the lists of all the kinds of objects which may be rendered for an instance.
+func InventoryLists() []client.ObjectList {
+	return []client.ObjectList{
+		&corev1.ServiceAccountList{},
+		&corev1.ConfigMapList{},
+		&rbacv1.RoleList{},
+		&rbacv1.RoleBindingList{},
+		&appsv1.DaemonSetList{},
+	}
+}
+
 func FromClient(ctx context.Context, cli client.Client, plat platform.Platform, mf rtemanifests.Manifests) ExistingManifests {
 	ret := ExistingManifests{
 		Existing: rtemanifests.New(plat),
@@ -124,8 +172,8 @@ func FromClient(ctx context.Context, cli client.Client, plat platform.Platform,
 	return ret
 }
 
-func NamespacedNameFromObject(obj client.Object) (topologyexporterv1alpha1.NamespacedName, bool) {
-	res := topologyexporterv1alpha1.NamespacedName{
+func NamespacedNameFromObject(obj client.Object) (topologyexporterv1beta1.NamespacedName, bool) {
+	res := topologyexporterv1beta1.NamespacedName{
 		Namespace: obj.GetNamespace(),
 		Name:      obj.GetName(),
 	}
@@ -133,8 +181,220 @@ func NamespacedNameFromObject(obj client.Object) (topologyexporterv1alpha1.Names
 	return res, ok
 }
 
+// FindExporterContainer returns the exporter container of the given DaemonSet, or nil if it's missing.
+func FindExporterContainer(ds *appsv1.DaemonSet) *corev1.Container {
+	containers := ds.Spec.Template.Spec.Containers
+	for idx := range containers {
+		if containers[idx].Name == ExporterContainerName {
+			return &containers[idx]
+		}
+	}
+	return nil
+}
+
 func UpdateDaemonSetImage(ds *appsv1.DaemonSet, pullSpec string) *appsv1.DaemonSet {
-	// TODO: better match by name than assume container#0 is RTE proper (not minion)
-	ds.Spec.Template.Spec.Containers[0].Image = pullSpec
+	if cnt := FindExporterContainer(ds); cnt != nil {
+		cnt.Image = pullSpec
+	}
+	return ds
+}
+
+// UpdateDaemonSetPullOptions sets how the exporter image is pulled. Empty options keep the manifest defaults.
+func UpdateDaemonSetPullOptions(ds *appsv1.DaemonSet, pullPolicy corev1.PullPolicy, pullSecrets []corev1.LocalObjectReference) *appsv1.DaemonSet {
+	podSpec := &ds.Spec.Template.Spec
+	if cnt := FindExporterContainer(ds); cnt != nil && pullPolicy != "" {
+		cnt.ImagePullPolicy = pullPolicy
+	}
+	if len(pullSecrets) > 0 {
+		podSpec.ImagePullSecrets = make([]corev1.LocalObjectReference, len(pullSecrets))
+		copy(podSpec.ImagePullSecrets, pullSecrets)
+	}
 	return ds
 }
+
+func UpdateDaemonSetPlacement(ds *appsv1.DaemonSet, nodeSelector map[string]string, tolerations []corev1.Toleration, affinity *corev1.Affinity) *appsv1.DaemonSet {
+	podSpec := &ds.Spec.Template.Spec
+	if len(nodeSelector) > 0 {
+		podSpec.NodeSelector = make(map[string]string, len(nodeSelector))
+		for key, val := range nodeSelector {
+			podSpec.NodeSelector[key] = val
+		}
+	}
+	if len(tolerations) > 0 {
+		podSpec.Tolerations = make([]corev1.Toleration, len(tolerations))
+		for idx := range tolerations {
+			tolerations[idx].DeepCopyInto(&podSpec.Tolerations[idx])
+		}
+	}
+	if affinity != nil {
+		podSpec.Affinity = affinity.DeepCopy()
+	}
+	return ds
+}
+
+// UpdateDaemonSetCommand sets the exporter flags from the spec, along with the volumes the kubelet state dirs need.
+func UpdateDaemonSetCommand(ds *appsv1.DaemonSet, spec topologyexporterv1beta1.ResourceTopologyExporterSpec) *appsv1.DaemonSet {
+	cnt := FindExporterContainer(ds)
+	if cnt == nil {
+		return ds
+	}
+	if spec.PollInterval != nil {
+		cnt.Command = setFlag(cnt.Command, flagSleepInterval, spec.PollInterval.Duration.String())
+	}
+	if spec.TopologyManagerPolicy != "" {
+		cnt.Command = setFlag(cnt.Command, flagTopologyManagerPolicy, string(spec.TopologyManagerPolicy))
+	}
+	if len(spec.KubeletStateDirs) > 0 {
+		cnt.Command = setFlag(cnt.Command, flagKubeletStateDir, spec.KubeletStateDirs...)
+		setKubeletStateVolumes(ds, cnt, spec.KubeletStateDirs)
+	}
+	if spec.ReferenceContainer != "" {
+		cnt.Command = setFlag(cnt.Command, flagReferenceContainer, spec.ReferenceContainer)
+	}
+	return ds
+}
+
+// setKubeletStateVolumes mounts read-only in the exporter container the given kubelet state dirs, replacing
+// the ones mounted before. The dirs are paths in the container: the ones under "/host-" are mounted from
+// the host path without that prefix, like /host-var/lib/kubelet from /var/lib/kubelet, the others from the same path.
+func setKubeletStateVolumes(ds *appsv1.DaemonSet, cnt *corev1.Container, dirs []string) {
+	podSpec := &ds.Spec.Template.Spec
+	volumes := make([]corev1.Volume, 0, len(podSpec.Volumes)+len(dirs))
+	for _, vol := range podSpec.Volumes {
+		if !strings.HasPrefix(vol.Name, kubeletStateVolumePrefix) {
+			volumes = append(volumes, vol)
+		}
+	}
+	mounts := make([]corev1.VolumeMount, 0, len(cnt.VolumeMounts)+len(dirs))
+	for _, mount := range cnt.VolumeMounts {
+		if !strings.HasPrefix(mount.Name, kubeletStateVolumePrefix) {
+			mounts = append(mounts, mount)
+		}
+	}
+
+	for idx, dir := range dirs {
+		name := fmt.Sprintf("%s%d", kubeletStateVolumePrefix, idx)
+		volumes = append(volumes, corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: kubeletStateHostPath(dir)},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{Name: name, MountPath: dir, ReadOnly: true})
+	}
+	podSpec.Volumes = volumes
+	cnt.VolumeMounts = mounts
+}
+
+func kubeletStateHostPath(dir string) string {
+	if strings.HasPrefix(dir, hostPathPrefix) {
+		return "/" + strings.TrimPrefix(dir, hostPathPrefix)
+	}
+	return dir
+}
+
+// setFlag replaces all the occurrences of flag in args with the given values.
+// The new values take the place of the first occurrence, or are appended if the flag is missing.
+func setFlag(args []string, flag string, values ...string) []string {
+	prefix := flag + "="
+	flagArgs := make([]string, 0, len(values))
+	for _, value := range values {
+		flagArgs = append(flagArgs, prefix+value)
+	}
+
+	res := make([]string, 0, len(args)+len(flagArgs))
+	found := false
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, prefix) {
+			res = append(res, arg)
+			continue
+		}
+		if !found {
+			res = append(res, flagArgs...)
+			found = true
+		}
+	}
+	if !found {
+		res = append(res, flagArgs...)
+	}
+	return res
+}
+
+func UpdateDaemonSetConfigHash(ds *appsv1.DaemonSet, cm *corev1.ConfigMap) *appsv1.DaemonSet {
+	if cm == nil {
+		return ds
+	}
+	if ds.Spec.Template.Annotations == nil {
+		ds.Spec.Template.Annotations = make(map[string]string)
+	}
+	ds.Spec.Template.Annotations[ConfigHashAnnotation] = configMapHash(cm)
+	return ds
+}
+
+func configMapHash(cm *corev1.ConfigMap) string {
+	keys := make([]string, 0, len(cm.Data))
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(cm.Data[key]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ObjectName returns the name of the object, created from the given manifest name, owned by the given instance.
+func ObjectName(baseName, instanceName string) string {
+	if instanceName == "" || instanceName == DefaultInstanceName {
+		return baseName
+	}
+	return baseName + "-" + instanceName
+}
+
+// UpdateNames makes the names of all the objects in the manifests unique for the given instance,
+// fixing all the cross references between the objects.
+func UpdateNames(mf rtemanifests.Manifests, instanceName string) rtemanifests.Manifests {
+	if mf.ServiceAccount != nil {
+		saName := mf.ServiceAccount.Name
+		mf.ServiceAccount.Name = ObjectName(saName, instanceName)
+		mf.DaemonSet.Spec.Template.Spec.ServiceAccountName = mf.ServiceAccount.Name
+		for idx := range mf.RoleBinding.Subjects {
+			sub := &mf.RoleBinding.Subjects[idx]
+			if sub.Kind == rbacv1.ServiceAccountKind && sub.Name == saName {
+				sub.Name = mf.ServiceAccount.Name
+			}
+		}
+	}
+
+	mf.Role.Name = ObjectName(mf.Role.Name, instanceName)
+	mf.RoleBinding.Name = ObjectName(mf.RoleBinding.Name, instanceName)
+	mf.RoleBinding.RoleRef.Name = mf.Role.Name
+
+	if mf.ConfigMap != nil {
+		cmName := mf.ConfigMap.Name
+		mf.ConfigMap.Name = ObjectName(cmName, instanceName)
+		for idx := range mf.DaemonSet.Spec.Template.Spec.Volumes {
+			vol := &mf.DaemonSet.Spec.Template.Spec.Volumes[idx]
+			if vol.ConfigMap != nil && vol.ConfigMap.Name == cmName {
+				vol.ConfigMap.Name = mf.ConfigMap.Name
+			}
+		}
+	}
+
+	mf.DaemonSet.Name = ObjectName(mf.DaemonSet.Name, instanceName)
+	// daemonsets must not select each other's pods
+	if mf.DaemonSet.Spec.Selector != nil {
+		for key, val := range mf.DaemonSet.Spec.Selector.MatchLabels {
+			mf.DaemonSet.Spec.Selector.MatchLabels[key] = ObjectName(val, instanceName)
+			if _, ok := mf.DaemonSet.Spec.Template.Labels[key]; ok {
+				mf.DaemonSet.Spec.Template.Labels[key] = ObjectName(val, instanceName)
+			}
+		}
+	}
+	return mf
+}
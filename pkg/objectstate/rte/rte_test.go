@@ -0,0 +1,363 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rte
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+func TestUpdateDaemonSetPlacement(t *testing.T) {
+	nodeSelector := map[string]string{
+		"node-role.kubernetes.io/worker": "",
+	}
+	tolerations := []corev1.Toleration{
+		{
+			Key:      "node-role.kubernetes.io/infra",
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		},
+	}
+	affinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{
+								Key:      "node-role.kubernetes.io/master",
+								Operator: corev1.NodeSelectorOpDoesNotExist,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("empty placement", func(t *testing.T) {
+		ds := UpdateDaemonSetPlacement(&appsv1.DaemonSet{}, nil, nil, nil)
+		if !equality.Semantic.DeepEqual(ds.Spec.Template.Spec, corev1.PodSpec{}) {
+			t.Errorf("unexpected pod spec changes: %v", ds.Spec.Template.Spec)
+		}
+	})
+
+	t.Run("full placement", func(t *testing.T) {
+		ds := UpdateDaemonSetPlacement(&appsv1.DaemonSet{}, nodeSelector, tolerations, affinity)
+		podSpec := ds.Spec.Template.Spec
+		if !equality.Semantic.DeepEqual(podSpec.NodeSelector, nodeSelector) {
+			t.Errorf("nodeSelector mismatch: %v", podSpec.NodeSelector)
+		}
+		if !equality.Semantic.DeepEqual(podSpec.Tolerations, tolerations) {
+			t.Errorf("tolerations mismatch: %v", podSpec.Tolerations)
+		}
+		if !equality.Semantic.DeepEqual(podSpec.Affinity, affinity) {
+			t.Errorf("affinity mismatch: %v", podSpec.Affinity)
+		}
+
+		// the daemonset must not alias the source data
+		podSpec.NodeSelector["foo"] = "bar"
+		if _, ok := nodeSelector["foo"]; ok {
+			t.Errorf("nodeSelector aliased by the daemonset")
+		}
+	})
+}
+
+func TestUpdateDaemonSetImage(t *testing.T) {
+	const image = "quay.io/example/resource-topology-exporter:test"
+
+	t.Run("exporter container", func(t *testing.T) {
+		ds := &appsv1.DaemonSet{}
+		ds.Spec.Template.Spec.Containers = []corev1.Container{
+			{Name: "kube-rbac-proxy", Image: "quay.io/brancz/kube-rbac-proxy:v0.8.0"},
+			{Name: ExporterContainerName, Image: "quay.io/k8stopologyawareschedwg/resource-topology-exporter:v0.2.5"},
+		}
+		UpdateDaemonSetImage(ds, image)
+		if got := ds.Spec.Template.Spec.Containers[1].Image; got != image {
+			t.Errorf("exporter image mismatch: %q", got)
+		}
+		if got := ds.Spec.Template.Spec.Containers[0].Image; got != "quay.io/brancz/kube-rbac-proxy:v0.8.0" {
+			t.Errorf("sidecar image changed: %q", got)
+		}
+	})
+
+	t.Run("missing exporter container", func(t *testing.T) {
+		ds := &appsv1.DaemonSet{}
+		ds.Spec.Template.Spec.Containers = []corev1.Container{
+			{Name: "kube-rbac-proxy", Image: "quay.io/brancz/kube-rbac-proxy:v0.8.0"},
+		}
+		UpdateDaemonSetImage(ds, image)
+		if got := ds.Spec.Template.Spec.Containers[0].Image; got != "quay.io/brancz/kube-rbac-proxy:v0.8.0" {
+			t.Errorf("sidecar image changed: %q", got)
+		}
+	})
+}
+
+func TestUpdateDaemonSetPullOptions(t *testing.T) {
+	newDaemonSet := func() *appsv1.DaemonSet {
+		ds := &appsv1.DaemonSet{}
+		ds.Spec.Template.Spec.Containers = []corev1.Container{
+			{
+				Name:            "kube-rbac-proxy",
+				ImagePullPolicy: corev1.PullIfNotPresent,
+			},
+			{
+				Name:            ExporterContainerName,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+			},
+		}
+		return ds
+	}
+
+	t.Run("empty options", func(t *testing.T) {
+		ds := UpdateDaemonSetPullOptions(newDaemonSet(), "", nil)
+		if !equality.Semantic.DeepEqual(ds, newDaemonSet()) {
+			t.Errorf("unexpected daemonset changes: %v", ds.Spec.Template.Spec)
+		}
+	})
+
+	t.Run("full options", func(t *testing.T) {
+		pullSecrets := []corev1.LocalObjectReference{{Name: "mirror-registry"}}
+		ds := UpdateDaemonSetPullOptions(newDaemonSet(), corev1.PullAlways, pullSecrets)
+		podSpec := ds.Spec.Template.Spec
+		if podSpec.Containers[1].ImagePullPolicy != corev1.PullAlways {
+			t.Errorf("pull policy mismatch: %v", podSpec.Containers[1].ImagePullPolicy)
+		}
+		if podSpec.Containers[0].ImagePullPolicy != corev1.PullIfNotPresent {
+			t.Errorf("sidecar pull policy changed: %v", podSpec.Containers[0].ImagePullPolicy)
+		}
+		if !equality.Semantic.DeepEqual(podSpec.ImagePullSecrets, pullSecrets) {
+			t.Errorf("pull secrets mismatch: %v", podSpec.ImagePullSecrets)
+		}
+
+		// the daemonset must not alias the source data
+		podSpec.ImagePullSecrets[0].Name = "foo"
+		if pullSecrets[0].Name != "mirror-registry" {
+			t.Errorf("pull secrets aliased by the daemonset")
+		}
+	})
+}
+
+func TestUpdateDaemonSetCommand(t *testing.T) {
+	baseCommand := []string{
+		"/bin/resource-topology-exporter",
+		"--export-namespace=rte",
+		"--sleep-interval=10s",
+		"--sysfs=/host-sys",
+		"--kubelet-state-dir=/host-var/lib/kubelet",
+		"--podresources-socket=unix:///host-var/lib/kubelet/pod-resources/kubelet.sock",
+		"--topology-manager-policy=single-numa-node",
+	}
+
+	type testCase struct {
+		description     string
+		spec            topologyexporterv1beta1.ResourceTopologyExporterSpec
+		expectedCommand []string
+	}
+
+	testCases := []testCase{
+		{
+			description:     "empty spec",
+			expectedCommand: baseCommand,
+		},
+		{
+			description: "all knobs",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				PollInterval:          &metav1.Duration{Duration: 30 * time.Second},
+				TopologyManagerPolicy: topologyexporterv1beta1.TopologyManagerPolicyRestricted,
+				KubeletStateDirs:      []string{"/host-var/lib/kubelet", "/host-var/lib/kubelet/device-plugins"},
+				ReferenceContainer:    "rte/rte-pod/shared-pool-container",
+			},
+			expectedCommand: []string{
+				"/bin/resource-topology-exporter",
+				"--export-namespace=rte",
+				"--sleep-interval=30s",
+				"--sysfs=/host-sys",
+				"--kubelet-state-dir=/host-var/lib/kubelet",
+				"--kubelet-state-dir=/host-var/lib/kubelet/device-plugins",
+				"--podresources-socket=unix:///host-var/lib/kubelet/pod-resources/kubelet.sock",
+				"--topology-manager-policy=restricted",
+				"--reference-container=rte/rte-pod/shared-pool-container",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ds := &appsv1.DaemonSet{}
+			ds.Spec.Template.Spec.Containers = []corev1.Container{
+				{
+					Name:    "kube-rbac-proxy",
+					Command: []string{"/usr/local/bin/kube-rbac-proxy"},
+				},
+				{
+					Name:    ExporterContainerName,
+					Command: append([]string{}, baseCommand...),
+				},
+			}
+			UpdateDaemonSetCommand(ds, tc.spec)
+			got := ds.Spec.Template.Spec.Containers[1].Command
+			if !reflect.DeepEqual(got, tc.expectedCommand) {
+				t.Errorf("command mismatch:\nexpected %v\ngot      %v", tc.expectedCommand, got)
+			}
+			sidecar := ds.Spec.Template.Spec.Containers[0].Command
+			if !reflect.DeepEqual(sidecar, []string{"/usr/local/bin/kube-rbac-proxy"}) {
+				t.Errorf("sidecar command changed: %v", sidecar)
+			}
+		})
+	}
+}
+
+func TestUpdateDaemonSetCommandKubeletStateDirs(t *testing.T) {
+	mf, err := rtemanifests.GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("cannot load the manifests: %v", err)
+	}
+	spec := topologyexporterv1beta1.ResourceTopologyExporterSpec{
+		KubeletStateDirs: []string{"/host-var/lib/kubelet", "/var/lib/kubelet/device-plugins"},
+	}
+	ds := mf.DaemonSet.DeepCopy()
+	UpdateDaemonSetCommand(ds, spec)
+	// rendering again must not pile up the volumes
+	UpdateDaemonSetCommand(ds, spec)
+
+	cnt := FindExporterContainer(ds)
+	var flags []string
+	for _, arg := range cnt.Command {
+		if strings.HasPrefix(arg, "--kubelet-state-dir=") {
+			flags = append(flags, arg)
+		}
+	}
+	expectedFlags := []string{"--kubelet-state-dir=/host-var/lib/kubelet", "--kubelet-state-dir=/var/lib/kubelet/device-plugins"}
+	if !reflect.DeepEqual(flags, expectedFlags) {
+		t.Errorf("flags mismatch:\nexpected %v\ngot      %v", expectedFlags, flags)
+	}
+
+	hostPaths := map[string]string{}
+	for _, vol := range ds.Spec.Template.Spec.Volumes {
+		if vol.HostPath != nil {
+			hostPaths[vol.Name] = vol.HostPath.Path
+		}
+	}
+	mounted := map[string]string{}
+	for _, mount := range cnt.VolumeMounts {
+		if !mount.ReadOnly {
+			continue
+		}
+		mounted[mount.MountPath] = hostPaths[mount.Name]
+	}
+	expectedMounts := map[string]string{
+		"/host-var/lib/kubelet":           "/var/lib/kubelet",
+		"/var/lib/kubelet/device-plugins": "/var/lib/kubelet/device-plugins",
+	}
+	for mountPath, hostPath := range expectedMounts {
+		if mounted[mountPath] != hostPath {
+			t.Errorf("%s: expected a read-only mount of %q, got %q", mountPath, hostPath, mounted[mountPath])
+		}
+	}
+	if got, expected := len(ds.Spec.Template.Spec.Volumes), len(mf.DaemonSet.Spec.Template.Spec.Volumes)+2; got != expected {
+		t.Errorf("expected %d volumes, got %d", expected, got)
+	}
+	if got, expected := len(cnt.VolumeMounts), len(FindExporterContainer(mf.DaemonSet).VolumeMounts)+2; got != expected {
+		t.Errorf("expected %d volume mounts, got %d", expected, got)
+	}
+}
+
+func TestFindExporterContainer(t *testing.T) {
+	for _, plat := range []platform.Platform{platform.Kubernetes, platform.OpenShift} {
+		mf, err := rtemanifests.GetManifests(plat)
+		if err != nil {
+			t.Fatalf("cannot load the manifests for %s: %v", plat, err)
+		}
+		if FindExporterContainer(mf.DaemonSet) == nil {
+			t.Errorf("no container %q in the %s manifests", ExporterContainerName, plat)
+		}
+	}
+}
+
+func TestUpdateNames(t *testing.T) {
+	mf, err := rtemanifests.GetManifests(platform.Kubernetes)
+	if err != nil {
+		t.Fatalf("cannot load the manifests: %v", err)
+	}
+	mf = mf.Update(rtemanifests.UpdateOptions{
+		ConfigData: "ExcludeList: {}\n",
+		Namespace:  "rte",
+	})
+
+	t.Run("default instance", func(t *testing.T) {
+		got := UpdateNames(mf.Clone(), DefaultInstanceName)
+		if got.DaemonSet.Name != mf.DaemonSet.Name || got.Role.Name != mf.Role.Name {
+			t.Errorf("default instance objects renamed: ds=%q role=%q", got.DaemonSet.Name, got.Role.Name)
+		}
+	})
+
+	t.Run("pool instance", func(t *testing.T) {
+		got := mf.Update(rtemanifests.UpdateOptions{
+			ConfigData: "ExcludeList: {}\n",
+			Namespace:  "rte",
+		})
+		got = UpdateNames(got, "pool-a")
+
+		podSpec := got.DaemonSet.Spec.Template.Spec
+		if podSpec.ServiceAccountName != got.ServiceAccount.Name {
+			t.Errorf("daemonset uses serviceaccount %q expected %q", podSpec.ServiceAccountName, got.ServiceAccount.Name)
+		}
+		if got.RoleBinding.RoleRef.Name != got.Role.Name {
+			t.Errorf("rolebinding refers to role %q expected %q", got.RoleBinding.RoleRef.Name, got.Role.Name)
+		}
+		for _, sub := range got.RoleBinding.Subjects {
+			if sub.Name != got.ServiceAccount.Name {
+				t.Errorf("rolebinding subject %q expected %q", sub.Name, got.ServiceAccount.Name)
+			}
+		}
+		foundVolume := false
+		for _, vol := range podSpec.Volumes {
+			if vol.ConfigMap != nil && vol.ConfigMap.Name == got.ConfigMap.Name {
+				foundVolume = true
+			}
+		}
+		if !foundVolume {
+			t.Errorf("daemonset does not mount configmap %q", got.ConfigMap.Name)
+		}
+		for key, val := range got.DaemonSet.Spec.Selector.MatchLabels {
+			if got.DaemonSet.Spec.Template.Labels[key] != val {
+				t.Errorf("daemonset selector %s=%s does not match the pod template", key, val)
+			}
+			if mf.DaemonSet.Spec.Selector.MatchLabels[key] == val {
+				t.Errorf("daemonset selector %s=%s not unique", key, val)
+			}
+		}
+		for _, obj := range got.ToObjects() {
+			if !strings.HasSuffix(obj.GetName(), "-pool-a") {
+				t.Errorf("object %q not renamed", obj.GetName())
+			}
+		}
+	})
+}
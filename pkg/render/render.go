@@ -0,0 +1,153 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Format is how the rendered objects are written out.
+type Format string
+
+const (
+	// FormatYAML is a YAML stream of objects.
+	FormatYAML Format = "yaml"
+	// FormatJSON is a JSON v1 List of objects.
+	FormatJSON Format = "json"
+	// FormatKustomize is a directory with a file per object and the kustomization listing them.
+	FormatKustomize Format = "kustomize"
+)
+
+// KustomizationFile is the name of the kustomization written in the kustomize directories.
+const KustomizationFile = "kustomization.yaml"
+
+func knownFormats() []Format {
+	return []Format{FormatYAML, FormatJSON, FormatKustomize}
+}
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	names := []string{}
+	for _, format := range knownFormats() {
+		if string(format) == name {
+			return format, nil
+		}
+		names = append(names, string(format))
+	}
+	return "", fmt.Errorf("unknown format %q, should be one of: %s", name, strings.Join(names, ", "))
+}
+
+// YAML writes the given objects as a YAML stream.
+func YAML(w io.Writer, objs []client.Object) error {
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSON writes the given objects as a JSON v1 List, as kubectl does.
+func JSON(w io.Writer, objs []client.Object) error {
+	list := metav1.List{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "List",
+		},
+		Items: make([]runtime.RawExtension, 0, len(objs)),
+	}
+	for _, obj := range objs {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		list.Items = append(list.Items, runtime.RawExtension{Raw: data})
+	}
+	data, err := json.MarshalIndent(list, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// kustomization is the subset of the kustomize configuration needed to list the rendered objects.
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// Kustomize writes the given objects in the given directory, one per file, along with the kustomization
+// listing them in the same order. The directory is created if missing.
+func Kustomize(dir string, objs []client.Object) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	kust := kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  make([]string, 0, len(objs)),
+	}
+	for _, obj := range objs {
+		name, err := fileName(obj)
+		if err != nil {
+			return err
+		}
+		if err := writeObject(filepath.Join(dir, name), obj); err != nil {
+			return err
+		}
+		kust.Resources = append(kust.Resources, name)
+	}
+	data, err := yaml.Marshal(kust)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, KustomizationFile), data, 0644)
+}
+
+// fileName returns the name of the file holding the given object, like kustomize names them.
+func fileName(obj client.Object) (string, error) {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		return "", fmt.Errorf("object %s has no kind", client.ObjectKeyFromObject(obj))
+	}
+	return strings.ToLower(fmt.Sprintf("%s_%s.yaml", kind, obj.GetName())), nil
+}
+
+func writeObject(path string, obj client.Object) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,144 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+func testObjects() []client.Object {
+	return []client.Object{
+		&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rte", Name: "rte-config"},
+			Data:       map[string]string{"config.yaml": "ExcludeList: {}\n"},
+		},
+		&appsv1.DaemonSet{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "rte", Name: "resource-topology-exporter"},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range knownFormats() {
+		got, err := ParseFormat(string(format))
+		if err != nil || got != format {
+			t.Errorf("format %q: got %q err %v", format, got, err)
+		}
+	}
+	if _, err := ParseFormat("toml"); err == nil {
+		t.Errorf("unknown format accepted")
+	}
+}
+
+func TestYAML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := YAML(&buf, testObjects()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	docs := strings.Split(strings.TrimPrefix(buf.String(), "---\n"), "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %d:\n%s", len(docs), buf.String())
+	}
+	cm := corev1.ConfigMap{}
+	if err := yaml.Unmarshal([]byte(docs[0]), &cm); err != nil {
+		t.Fatalf("cannot decode the first document: %v", err)
+	}
+	if cm.Kind != "ConfigMap" || cm.Name != "rte-config" {
+		t.Errorf("unexpected first document: %s", docs[0])
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := JSON(&buf, testObjects()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list := struct {
+		APIVersion string            `json:"apiVersion"`
+		Kind       string            `json:"kind"`
+		Items      []metav1.TypeMeta `json:"items"`
+	}{}
+	if err := json.Unmarshal(buf.Bytes(), &list); err != nil {
+		t.Fatalf("cannot decode the list: %v\n%s", err, buf.String())
+	}
+	if list.APIVersion != "v1" || list.Kind != "List" {
+		t.Errorf("unexpected list type: %s/%s", list.APIVersion, list.Kind)
+	}
+	expected := []metav1.TypeMeta{
+		{APIVersion: "v1", Kind: "ConfigMap"},
+		{APIVersion: "apps/v1", Kind: "DaemonSet"},
+	}
+	if !reflect.DeepEqual(list.Items, expected) {
+		t.Errorf("expected items %v got %v", expected, list.Items)
+	}
+}
+
+func TestKustomize(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rte")
+	if err := Kustomize(dir, testObjects()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, KustomizationFile))
+	if err != nil {
+		t.Fatalf("cannot read the kustomization: %v", err)
+	}
+	kust := kustomization{}
+	if err := yaml.Unmarshal(data, &kust); err != nil {
+		t.Fatalf("cannot decode the kustomization: %v", err)
+	}
+	expected := []string{"configmap_rte-config.yaml", "daemonset_resource-topology-exporter.yaml"}
+	if !reflect.DeepEqual(kust.Resources, expected) {
+		t.Errorf("expected resources %v got %v", expected, kust.Resources)
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, expected[1]))
+	if err != nil {
+		t.Fatalf("cannot read the DaemonSet: %v", err)
+	}
+	ds := appsv1.DaemonSet{}
+	if err := yaml.Unmarshal(data, &ds); err != nil {
+		t.Fatalf("cannot decode the DaemonSet: %v", err)
+	}
+	if ds.Name != "resource-topology-exporter" || ds.Namespace != "rte" {
+		t.Errorf("unexpected DaemonSet: %s", data)
+	}
+}
+
+func TestKustomizeNoKind(t *testing.T) {
+	objs := []client.Object{
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "rte", Name: "rte-config"}},
+	}
+	if err := Kustomize(t.TempDir(), objs); err == nil {
+		t.Errorf("object without kind accepted")
+	}
+}
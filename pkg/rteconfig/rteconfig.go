@@ -0,0 +1,64 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rteconfig
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift-kni/resource-topology-exporter/pkg/config"
+	"github.com/openshift-kni/resource-topology-exporter/pkg/sysinfo"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+	"github.com/openshift-kni/rte-operator/pkg/validation"
+)
+
+// Validate checks the given configuration is consumable by the exporter.
+// A nil configuration is valid.
+func Validate(conf *topologyexporterv1beta1.ExporterConfig) error {
+	return validation.ValidateExporterConfig(conf, field.NewPath("spec", "config")).ToAggregate()
+}
+
+// Render validates and serializes the given configuration in the format the exporter expects.
+// Returns empty data if there is no configuration to render.
+func Render(conf *topologyexporterv1beta1.ExporterConfig) (string, error) {
+	if conf == nil {
+		return "", nil
+	}
+	if err := Validate(conf); err != nil {
+		return "", err
+	}
+
+	rteConf := config.Config{
+		ExcludeList: conf.ExcludeList,
+	}
+	if conf.Resources != nil {
+		rteConf.Resources = sysinfo.Config{
+			ReservedCPUs:    conf.Resources.ReservedCPUs,
+			ResourceMapping: conf.Resources.ResourceMapping,
+		}
+	}
+	if len(rteConf.ExcludeList) == 0 && rteConf.Resources.IsEmpty() {
+		return "", nil
+	}
+
+	data, err := yaml.Marshal(rteConf)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,150 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package rteconfig
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift-kni/resource-topology-exporter/pkg/config"
+	"github.com/openshift-kni/resource-topology-exporter/pkg/sysinfo"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		description string
+		conf        *topologyexporterv1beta1.ExporterConfig
+		expectedErr bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "nil config",
+		},
+		{
+			description: "valid config",
+			conf: &topologyexporterv1beta1.ExporterConfig{
+				ExcludeList: map[string][]string{
+					"*": {"memory"},
+				},
+				Resources: &topologyexporterv1beta1.ResourcesConfig{
+					ReservedCPUs: "0-1,8",
+					ResourceMapping: map[string]string{
+						"8086:1520": "openshift.io/intelsriov",
+						"15b3":      "openshift.io/mlxsriov",
+					},
+				},
+			},
+		},
+		{
+			description: "bad cpu list",
+			conf: &topologyexporterv1beta1.ExporterConfig{
+				Resources: &topologyexporterv1beta1.ResourcesConfig{
+					ReservedCPUs: "0-a",
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "reversed cpu range",
+			conf: &topologyexporterv1beta1.ExporterConfig{
+				Resources: &topologyexporterv1beta1.ResourcesConfig{
+					ReservedCPUs: "4-2",
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "malformed pci id",
+			conf: &topologyexporterv1beta1.ExporterConfig{
+				Resources: &topologyexporterv1beta1.ResourcesConfig{
+					ResourceMapping: map[string]string{
+						"8086-1520": "openshift.io/intelsriov",
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "empty resource name",
+			conf: &topologyexporterv1beta1.ExporterConfig{
+				Resources: &topologyexporterv1beta1.ResourcesConfig{
+					ResourceMapping: map[string]string{
+						"8086:1520": "",
+					},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := Validate(tc.conf)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error=%t got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestRenderRoundTrip(t *testing.T) {
+	conf := &topologyexporterv1beta1.ExporterConfig{
+		ExcludeList: map[string][]string{
+			"node-1": {"cpu", "openshift.io/intelsriov"},
+		},
+		Resources: &topologyexporterv1beta1.ResourcesConfig{
+			ReservedCPUs: "0,1",
+			ResourceMapping: map[string]string{
+				"8086:1520": "openshift.io/intelsriov",
+			},
+		},
+	}
+
+	data, err := Render(conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := config.Config{}
+	if err := yaml.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("cannot decode rendered data: %v", err)
+	}
+	expected := config.Config{
+		ExcludeList: conf.ExcludeList,
+		Resources: sysinfo.Config{
+			ReservedCPUs:    conf.Resources.ReservedCPUs,
+			ResourceMapping: conf.Resources.ResourceMapping,
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("config mismatch: expected %+v got %+v", expected, got)
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	for _, conf := range []*topologyexporterv1beta1.ExporterConfig{nil, {}} {
+		data, err := Render(conf)
+		if err != nil || data != "" {
+			t.Errorf("unexpected render result for %v: data=%q err=%v", conf, data, err)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"sort"
+
+	nrtv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+const (
+	// ReasonTopologyMissing is set when a node running the exporter has no NodeResourceTopology object.
+	ReasonTopologyMissing Reason = "TopologyMissing"
+	// ReasonExporterNotReady is set when nobody is keeping the NodeResourceTopology object of a node up to date.
+	ReasonExporterNotReady Reason = "ExporterNotReady"
+)
+
+// RelatedObjects returns the references to the given objects, which must carry their GVK.
+func RelatedObjects(objs []k8sclient.Object) []topologyexporterv1beta1.ObjectReference {
+	refs := []topologyexporterv1beta1.ObjectReference{}
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		refs = append(refs, topologyexporterv1beta1.ObjectReference{
+			Group:     gvk.Group,
+			Kind:      gvk.Kind,
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+		})
+	}
+	return refs
+}
+
+// UpdateDaemonSetCounts copies the exporter pod counts from the given DaemonSet status.
+func UpdateDaemonSetCounts(st *topologyexporterv1beta1.ResourceTopologyExporterStatus, ds *appsv1.DaemonSet) {
+	st.DesiredNumberScheduled = ds.Status.DesiredNumberScheduled
+	st.NumberReady = ds.Status.NumberReady
+	st.UpdatedNumberScheduled = ds.Status.UpdatedNumberScheduled
+}
+
+// NodeTopology reports, for each node running an exporter pod, if its NodeResourceTopology object
+// is fresh, meaning it exists and a ready exporter pod is keeping it up to date.
+// Returns the node reports sorted by node name and the count of nodes whose object is not fresh.
+func NodeTopology(pods []corev1.Pod, nrts []nrtv1alpha1.NodeResourceTopology) ([]topologyexporterv1beta1.NodeTopologyStatus, int32) {
+	nrtByNode := make(map[string]*nrtv1alpha1.NodeResourceTopology)
+	for idx := range nrts {
+		nrtByNode[nrts[idx].Name] = &nrts[idx]
+	}
+
+	readyByNode := make(map[string]bool)
+	for idx := range pods {
+		pod := &pods[idx]
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		readyByNode[pod.Spec.NodeName] = readyByNode[pod.Spec.NodeName] || isPodReady(pod)
+	}
+
+	nodeNames := make([]string, 0, len(readyByNode))
+	for nodeName := range readyByNode {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	var stale int32
+	ret := make([]topologyexporterv1beta1.NodeTopologyStatus, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		nts := topologyexporterv1beta1.NodeTopologyStatus{
+			NodeName: nodeName,
+		}
+		nrt, ok := nrtByNode[nodeName]
+		if ok {
+			nts.LastUpdateTime = lastUpdateTime(nrt)
+		}
+		switch {
+		case !ok:
+			nts.Reason = string(ReasonTopologyMissing)
+		case !readyByNode[nodeName]:
+			nts.Reason = string(ReasonExporterNotReady)
+		default:
+			nts.Fresh = true
+		}
+		if !nts.Fresh {
+			stale++
+		}
+		ret = append(ret, nts)
+	}
+	return ret, stale
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// lastUpdateTime approximates the last change of the object using its managed fields,
+// because NodeResourceTopology objects carry no explicit timestamp.
+func lastUpdateTime(obj metav1.Object) *metav1.Time {
+	ts := obj.GetCreationTimestamp()
+	for _, mf := range obj.GetManagedFields() {
+		if mf.Time != nil && ts.Before(mf.Time) {
+			ts = *mf.Time
+		}
+	}
+	if ts.IsZero() {
+		return nil
+	}
+	return &ts
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	nrtv1alpha1 "github.com/k8stopologyawareschedwg/noderesourcetopology-api/pkg/apis/topology/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+func TestNodeTopology(t *testing.T) {
+	created := metav1.NewTime(time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC))
+	updated := metav1.NewTime(created.Add(time.Hour))
+
+	pods := []corev1.Pod{
+		makePod("node-a", true),
+		makePod("node-b", false),
+		makePod("node-c", true),
+		// rolling update: a node may briefly run two exporter pods
+		makePod("node-c", false),
+		// not scheduled yet
+		makePod("", false),
+	}
+	nrts := []nrtv1alpha1.NodeResourceTopology{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "node-a",
+				CreationTimestamp: created,
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: "resource-topology-exporter", Time: &updated},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "node-b",
+				CreationTimestamp: created,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "node-z",
+				CreationTimestamp: created,
+			},
+		},
+	}
+
+	got, stale := NodeTopology(pods, nrts)
+	expected := []topologyexporterv1beta1.NodeTopologyStatus{
+		{
+			NodeName:       "node-a",
+			Fresh:          true,
+			LastUpdateTime: &updated,
+		},
+		{
+			NodeName:       "node-b",
+			Reason:         string(ReasonExporterNotReady),
+			LastUpdateTime: &created,
+		},
+		{
+			NodeName: "node-c",
+			Reason:   string(ReasonTopologyMissing),
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("node topology mismatch:\nexpected %+v\ngot      %+v", expected, got)
+	}
+	if stale != 2 {
+		t.Errorf("expected 2 stale nodes, got %d", stale)
+	}
+}
+
+func TestNodeTopologyEmpty(t *testing.T) {
+	got, stale := NodeTopology(nil, nil)
+	if len(got) != 0 || stale != 0 {
+		t.Errorf("unexpected node topology for no pods: %v stale=%d", got, stale)
+	}
+}
+
+func makePod(nodeName string, ready bool) corev1.Pod {
+	cond := corev1.ConditionFalse
+	if ready {
+		cond = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: cond},
+			},
+		},
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"errors"
+)
+
+// Reason tells why a condition has its status. Reasons are part of the API: alerting rules match on them.
+type Reason string
+
+const (
+	// ReasonAsExpected is set on the conditions which report nothing worth attention.
+	ReasonAsExpected Reason = "AsExpected"
+	// ReasonReconcileFailed is set on failures no more specific reason describes.
+	ReasonReconcileFailed Reason = "ReconcileFailed"
+	// ReasonInvalidConfig is set when the spec of the instance can't be rendered.
+	ReasonInvalidConfig Reason = "InvalidConfig"
+	// ReasonOverlappingNodeSelector is set when an older instance may run the exporter on the same nodes.
+	ReasonOverlappingNodeSelector Reason = "OverlappingNodeSelector"
+	// ReasonAPISyncFailed is set when the NodeResourceTopology CRD can't be created or updated.
+	ReasonAPISyncFailed Reason = "APISyncFailed"
+	// ReasonRBACSyncFailed is set when the exporter service account, role or role binding can't be created or updated.
+	ReasonRBACSyncFailed Reason = "RBACSyncFailed"
+	// ReasonConfigSyncFailed is set when the exporter configuration can't be created or updated.
+	ReasonConfigSyncFailed Reason = "ConfigSyncFailed"
+	// ReasonDaemonSetSyncFailed is set when the exporter DaemonSet can't be created or updated.
+	ReasonDaemonSetSyncFailed Reason = "DaemonSetSyncFailed"
+	// ReasonPruneFailed is set when the objects no longer needed can't be deleted.
+	ReasonPruneFailed Reason = "PruneFailed"
+	// ReasonApplyConflict is set while the changes other field managers made to the fields the operator owns are reverted.
+	ReasonApplyConflict Reason = "ApplyConflict"
+	// ReasonDaemonSetNotReady is set while not all the exporter pods are ready.
+	ReasonDaemonSetNotReady Reason = "DaemonSetNotReady"
+	// ReasonExporterStatusFailed is set when the state of the exporter pods can't be read.
+	ReasonExporterStatusFailed Reason = "ExporterStatusFailed"
+	// ReasonImageResolutionFailed is set when the exporter image can't be resolved, so it is deployed as requested.
+	ReasonImageResolutionFailed Reason = "ImageResolutionFailed"
+	// ReasonRolloutInProgress is set when not all the exporter pods run the current DaemonSet template.
+	ReasonRolloutInProgress Reason = "RolloutInProgress"
+	// ReasonAPIVersionMismatch is set when the NodeResourceTopology CRD doesn't match the API version the exporter writes.
+	ReasonAPIVersionMismatch Reason = "APIVersionMismatch"
+	// ReasonDryRun is set when running in dry-run mode and the cluster state differs from the desired one.
+	ReasonDryRun Reason = "DryRun"
+	// ReasonUnmanaged is set when the management state of the instance tells the operator to leave the exporter alone.
+	ReasonUnmanaged Reason = "Unmanaged"
+	// ReasonRemoved is set once the exporter is removed as the management state of the instance requires.
+	ReasonRemoved Reason = "Removed"
+	// ReasonRemoveFailed is set when the exporter can't be removed.
+	ReasonRemoveFailed Reason = "RemoveFailed"
+	// ReasonUninstalling is set while the uninstall policy of a deleted instance runs.
+	ReasonUninstalling Reason = "Uninstalling"
+	// ReasonUninstallFailed is set when the uninstall policy of a deleted instance can't be carried out.
+	ReasonUninstallFailed Reason = "UninstallFailed"
+)
+
+// Error is a failure along with the reason to report it with in the conditions.
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WrapError attaches the given reason to the given error. Returns nil if the error is nil.
+func WrapError(reason Reason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Reason: reason, Err: err}
+}
+
+// ReasonFromError returns the reason and the message to report the given error with.
+// Errors carrying no reason are reported with ReasonReconcileFailed.
+func ReasonFromError(err error) (Reason, string) {
+	if err == nil {
+		return ReasonAsExpected, ""
+	}
+	var statusErr *Error
+	if errors.As(err, &statusErr) {
+		return statusErr.Reason, err.Error()
+	}
+	return ReasonReconcileFailed, err.Error()
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestReasonFromError(t *testing.T) {
+	type testCase struct {
+		description     string
+		err             error
+		expectedReason  Reason
+		expectedMessage string
+	}
+
+	testCases := []testCase{
+		{
+			description:    "no error",
+			expectedReason: ReasonAsExpected,
+		},
+		{
+			description:     "bare error",
+			err:             fmt.Errorf("boom"),
+			expectedReason:  ReasonReconcileFailed,
+			expectedMessage: "boom",
+		},
+		{
+			description:     "error with reason",
+			err:             WrapError(ReasonRBACSyncFailed, errors.Wrapf(fmt.Errorf("forbidden"), "could not apply role")),
+			expectedReason:  ReasonRBACSyncFailed,
+			expectedMessage: "could not apply role: forbidden",
+		},
+		{
+			description:     "wrapped error with reason",
+			err:             errors.Wrapf(WrapError(ReasonPruneFailed, fmt.Errorf("forbidden")), "sync failed"),
+			expectedReason:  ReasonPruneFailed,
+			expectedMessage: "sync failed: forbidden",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			reason, message := ReasonFromError(tc.err)
+			if reason != tc.expectedReason || message != tc.expectedMessage {
+				t.Errorf("expected %q %q got %q %q", tc.expectedReason, tc.expectedMessage, reason, message)
+			}
+		})
+	}
+
+	if WrapError(ReasonPruneFailed, nil) != nil {
+		t.Errorf("wrapping a nil error must give a nil error")
+	}
+}
@@ -18,16 +18,19 @@ package status
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 
-	topologyexporterv1alpha1 "github.com/openshift-kni/rte-operator/api/v1alpha1"
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
 )
 
 // TODO: are we duping these?
@@ -36,14 +39,26 @@ const (
 	ConditionProgressing = "Progressing"
 	ConditionDegraded    = "Degraded"
 	ConditionUpgradeable = "Upgradeable"
+	// ConditionImageResolved is only set for the exporter images selected in the instance.
+	ConditionImageResolved = "ImageResolved"
 )
 
-func Update(ctx context.Context, client k8sclient.Client, rte *topologyexporterv1alpha1.ResourceTopologyExporter, condition string, reason string, message string) error {
-	conditions := getConditions(condition, reason, message)
-	if equality.Semantic.DeepEqual(conditions, rte.Status.Conditions) {
+// UpgradeBlocker tells why upgrading the operator is not safe at the moment.
+type UpgradeBlocker struct {
+	Reason  Reason
+	Message string
+}
+
+// Update sets the given condition, along with the Upgradeable condition, in the status of the instance,
+// then writes the status unless it is already up to date on the cluster.
+// The exporter rollout is always checked; the blockers tell the other reasons the operator should not be upgraded.
+func Update(ctx context.Context, client k8sclient.Client, rte *topologyexporterv1beta1.ResourceTopologyExporter, condition string, reason Reason, message string, blockers ...UpgradeBlocker) error {
+	SetConditions(&rte.Status, rte.Generation, condition, reason, message, blockers...)
+
+	live := &topologyexporterv1beta1.ResourceTopologyExporter{}
+	if err := client.Get(ctx, k8sclient.ObjectKeyFromObject(rte), live); err == nil && equality.Semantic.DeepEqual(live.Status, rte.Status) {
 		return nil
 	}
-	rte.Status.Conditions = getConditions(condition, reason, message)
 
 	if err := client.Status().Update(ctx, rte); err != nil {
 		return errors.Wrapf(err, "could not update status for object %s", k8sclient.ObjectKeyFromObject(rte))
@@ -51,61 +66,128 @@ func Update(ctx context.Context, client k8sclient.Client, rte *topologyexporterv
 	return nil
 }
 
+// SetConditions sets the given condition to true with the given reason and message, and the other ones accordingly,
+// as observed at the given generation of the instance. The transition times change only along with the condition statuses.
+func SetConditions(st *topologyexporterv1beta1.ResourceTopologyExporterStatus, generation int64, condition string, reason Reason, message string, blockers ...UpgradeBlocker) {
+	st.ObservedGeneration = generation
+
+	available := metav1.Condition{
+		Type:    ConditionAvailable,
+		Status:  metav1.ConditionFalse,
+		Reason:  string(reason),
+		Message: message,
+	}
+	if condition == ConditionAvailable {
+		available.Status = metav1.ConditionTrue
+	}
+	conditions := []metav1.Condition{
+		available,
+		upgradeableCondition(st, blockers),
+		activeCondition(ConditionProgressing, condition, reason, message),
+		activeCondition(ConditionDegraded, condition, reason, message),
+	}
+	for _, cond := range conditions {
+		cond.ObservedGeneration = generation
+		meta.SetStatusCondition(&st.Conditions, cond)
+	}
+}
+
+// SetImageResolved reports if the exporter image selected in the instance could be resolved to a digest, as observed
+// at the given generation. Failing to resolve it is only a warning: the image is deployed as requested meanwhile.
+func SetImageResolved(st *topologyexporterv1beta1.ResourceTopologyExporterStatus, generation int64, err error) {
+	cond := metav1.Condition{
+		Type:               ConditionImageResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             string(ReasonAsExpected),
+		ObservedGeneration: generation,
+	}
+	if err != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = string(ReasonImageResolutionFailed)
+		cond.Message = err.Error()
+	}
+	meta.SetStatusCondition(&st.Conditions, cond)
+}
+
 func FindCondition(conditions []metav1.Condition, condition string) *metav1.Condition {
-	for idx := 0; idx < len(conditions); idx++ {
-		cond := &conditions[idx]
-		if cond.Type == condition {
-			return cond
+	return meta.FindStatusCondition(conditions, condition)
+}
+
+// ChangedConditions returns the conditions whose status differs from the one among the old conditions, or which are new.
+func ChangedConditions(oldConditions, newConditions []metav1.Condition) []metav1.Condition {
+	ret := []metav1.Condition{}
+	for _, cond := range newConditions {
+		oldCond := meta.FindStatusCondition(oldConditions, cond.Type)
+		if oldCond == nil || oldCond.Status != cond.Status {
+			ret = append(ret, cond)
+		}
+	}
+	return ret
+}
+
+// NRTAPIBlocker returns the blocker, if any, due to the NodeResourceTopology CRD not serving the API version
+// the exporter writes, or no longer serving a version some objects are still stored at.
+func NRTAPIBlocker(crd *apiextensionsv1.CustomResourceDefinition, version string) *UpgradeBlocker {
+	served := make(map[string]bool)
+	for _, ver := range crd.Spec.Versions {
+		served[ver.Name] = ver.Served
+	}
+	if !served[version] {
+		return &UpgradeBlocker{
+			Reason:  ReasonAPIVersionMismatch,
+			Message: fmt.Sprintf("the CRD %s does not serve the version %s", crd.Name, version),
+		}
+	}
+	for _, ver := range crd.Status.StoredVersions {
+		if !served[ver] {
+			return &UpgradeBlocker{
+				Reason:  ReasonAPIVersionMismatch,
+				Message: fmt.Sprintf("the CRD %s has objects stored at the version %s, which is no longer served", crd.Name, ver),
+			}
 		}
 	}
 	return nil
 }
 
-func getConditions(condition string, reason string, message string) []metav1.Condition {
-	conditions := getBaseConditions()
-	switch condition {
-	case ConditionAvailable:
-		conditions[0].Status = metav1.ConditionTrue
-		conditions[1].Status = metav1.ConditionTrue
-	case ConditionProgressing:
-		conditions[2].Status = metav1.ConditionTrue
-		conditions[2].Reason = reason
-		conditions[2].Message = message
-	case ConditionDegraded:
-		conditions[3].Status = metav1.ConditionTrue
-		conditions[3].Reason = reason
-		conditions[3].Message = message
-	}
-	return conditions
+func activeCondition(condType, condition string, reason Reason, message string) metav1.Condition {
+	if condType != condition {
+		return metav1.Condition{
+			Type:   condType,
+			Status: metav1.ConditionFalse,
+			Reason: string(ReasonAsExpected),
+		}
+	}
+	return metav1.Condition{
+		Type:    condType,
+		Status:  metav1.ConditionTrue,
+		Reason:  string(reason),
+		Message: message,
+	}
 }
 
-func getBaseConditions() []metav1.Condition {
-	now := time.Now()
-	return []metav1.Condition{
-		{
-			Type:               ConditionAvailable,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionAvailable,
-		},
-		{
-			Type:               ConditionUpgradeable,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionUpgradeable,
-		},
-		{
-			Type:               ConditionProgressing,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionProgressing,
-		},
-		{
-			Type:               ConditionDegraded,
-			Status:             metav1.ConditionFalse,
-			LastTransitionTime: metav1.Time{Time: now},
-			Reason:             ConditionDegraded,
-		},
+func upgradeableCondition(st *topologyexporterv1beta1.ResourceTopologyExporterStatus, blockers []UpgradeBlocker) metav1.Condition {
+	if st.UpdatedNumberScheduled < st.DesiredNumberScheduled {
+		blockers = append([]UpgradeBlocker{{
+			Reason:  ReasonRolloutInProgress,
+			Message: fmt.Sprintf("%d out of %d exporter pods are up to date", st.UpdatedNumberScheduled, st.DesiredNumberScheduled),
+		}}, blockers...)
+	}
+	if len(blockers) == 0 {
+		return metav1.Condition{
+			Type:   ConditionUpgradeable,
+			Status: metav1.ConditionTrue,
+			Reason: string(ReasonAsExpected),
+		}
+	}
+	messages := make([]string, 0, len(blockers))
+	for _, blocker := range blockers {
+		messages = append(messages, blocker.Message)
+	}
+	return metav1.Condition{
+		Type:    ConditionUpgradeable,
+		Status:  metav1.ConditionFalse,
+		Reason:  string(blockers[0].Reason),
+		Message: strings.Join(messages, "; "),
 	}
 }
 
@@ -0,0 +1,193 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+func TestSetConditions(t *testing.T) {
+	st := topologyexporterv1beta1.ResourceTopologyExporterStatus{}
+	SetConditions(&st, 1, ConditionProgressing, "Progressing", "waiting for the exporter pods")
+	checkCondition(t, st.Conditions, ConditionAvailable, metav1.ConditionFalse, "Progressing", 1)
+	checkCondition(t, st.Conditions, ConditionProgressing, metav1.ConditionTrue, "Progressing", 1)
+	checkCondition(t, st.Conditions, ConditionDegraded, metav1.ConditionFalse, ReasonAsExpected, 1)
+	checkCondition(t, st.Conditions, ConditionUpgradeable, metav1.ConditionTrue, ReasonAsExpected, 1)
+	if st.ObservedGeneration != 1 {
+		t.Errorf("unexpected observed generation: %d", st.ObservedGeneration)
+	}
+
+	// pretend the conditions were set a while ago, to tell which transition times get updated
+	past := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	for idx := range st.Conditions {
+		st.Conditions[idx].LastTransitionTime = past
+	}
+
+	SetConditions(&st, 2, ConditionDegraded, "ApplyConflict", "conflict on the daemonset")
+	checkCondition(t, st.Conditions, ConditionAvailable, metav1.ConditionFalse, "ApplyConflict", 2)
+	checkCondition(t, st.Conditions, ConditionProgressing, metav1.ConditionFalse, ReasonAsExpected, 2)
+	checkCondition(t, st.Conditions, ConditionDegraded, metav1.ConditionTrue, "ApplyConflict", 2)
+	if cond := FindCondition(st.Conditions, ConditionDegraded); cond.Message != "conflict on the daemonset" {
+		t.Errorf("unexpected degraded message: %q", cond.Message)
+	}
+
+	for _, condType := range []string{ConditionAvailable, ConditionUpgradeable} {
+		if cond := FindCondition(st.Conditions, condType); !cond.LastTransitionTime.Equal(&past) {
+			t.Errorf("condition %s transition time changed without a status change: %v", condType, cond.LastTransitionTime)
+		}
+	}
+	for _, condType := range []string{ConditionProgressing, ConditionDegraded} {
+		if cond := FindCondition(st.Conditions, condType); cond.LastTransitionTime.Equal(&past) {
+			t.Errorf("condition %s transition time not updated on status change", condType)
+		}
+	}
+	if len(st.Conditions) != 4 {
+		t.Errorf("unexpected conditions: %v", st.Conditions)
+	}
+}
+
+func TestSetConditionsUpgradeable(t *testing.T) {
+	st := topologyexporterv1beta1.ResourceTopologyExporterStatus{
+		DesiredNumberScheduled: 3,
+		UpdatedNumberScheduled: 1,
+	}
+	SetConditions(&st, 1, ConditionAvailable, ConditionAvailable, "")
+	checkCondition(t, st.Conditions, ConditionAvailable, metav1.ConditionTrue, ConditionAvailable, 1)
+	checkCondition(t, st.Conditions, ConditionUpgradeable, metav1.ConditionFalse, ReasonRolloutInProgress, 1)
+
+	st.UpdatedNumberScheduled = 3
+	blocker := UpgradeBlocker{Reason: ReasonAPIVersionMismatch, Message: "mismatch"}
+	SetConditions(&st, 1, ConditionAvailable, ConditionAvailable, "", blocker)
+	checkCondition(t, st.Conditions, ConditionUpgradeable, metav1.ConditionFalse, ReasonAPIVersionMismatch, 1)
+
+	SetConditions(&st, 1, ConditionAvailable, ConditionAvailable, "")
+	checkCondition(t, st.Conditions, ConditionUpgradeable, metav1.ConditionTrue, ReasonAsExpected, 1)
+}
+
+func TestSetImageResolved(t *testing.T) {
+	st := topologyexporterv1beta1.ResourceTopologyExporterStatus{}
+	SetConditions(&st, 1, ConditionAvailable, ConditionAvailable, "")
+	SetImageResolved(&st, 1, errors.New("registry unreachable"))
+	checkCondition(t, st.Conditions, ConditionImageResolved, metav1.ConditionFalse, ReasonImageResolutionFailed, 1)
+	if cond := FindCondition(st.Conditions, ConditionImageResolved); cond.Message != "registry unreachable" {
+		t.Errorf("unexpected image resolution message: %q", cond.Message)
+	}
+
+	// the resolution failures are warnings, not failures
+	checkCondition(t, st.Conditions, ConditionAvailable, metav1.ConditionTrue, ConditionAvailable, 1)
+	checkCondition(t, st.Conditions, ConditionDegraded, metav1.ConditionFalse, ReasonAsExpected, 1)
+
+	SetConditions(&st, 2, ConditionAvailable, ConditionAvailable, "")
+	SetImageResolved(&st, 2, nil)
+	checkCondition(t, st.Conditions, ConditionImageResolved, metav1.ConditionTrue, ReasonAsExpected, 2)
+}
+
+func TestChangedConditions(t *testing.T) {
+	st := topologyexporterv1beta1.ResourceTopologyExporterStatus{}
+	SetConditions(&st, 1, ConditionProgressing, ReasonDaemonSetNotReady, "0 out of 3 exporter pods are ready")
+	if changed := ChangedConditions(nil, st.Conditions); len(changed) != 4 {
+		t.Errorf("new conditions not reported as changed: %v", changed)
+	}
+
+	oldConditions := make([]metav1.Condition, len(st.Conditions))
+	copy(oldConditions, st.Conditions)
+	SetConditions(&st, 1, ConditionProgressing, ReasonDaemonSetNotReady, "1 out of 3 exporter pods are ready")
+	if changed := ChangedConditions(oldConditions, st.Conditions); len(changed) != 0 {
+		t.Errorf("message changes reported as transitions: %v", changed)
+	}
+
+	SetConditions(&st, 1, ConditionAvailable, ReasonAsExpected, "")
+	changed := ChangedConditions(oldConditions, st.Conditions)
+	got := []string{}
+	for _, cond := range changed {
+		got = append(got, cond.Type)
+	}
+	expected := []string{ConditionAvailable, ConditionProgressing}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected changed conditions %v got %v", expected, got)
+	}
+}
+
+func TestNRTAPIBlocker(t *testing.T) {
+	type testCase struct {
+		description    string
+		versions       []apiextensionsv1.CustomResourceDefinitionVersion
+		storedVersions []string
+		expectedBlock  bool
+	}
+
+	testCases := []testCase{
+		{
+			description:    "version served",
+			versions:       []apiextensionsv1.CustomResourceDefinitionVersion{{Name: "v1alpha1", Served: true, Storage: true}},
+			storedVersions: []string{"v1alpha1"},
+		},
+		{
+			description:   "version missing",
+			versions:      []apiextensionsv1.CustomResourceDefinitionVersion{{Name: "v1beta1", Served: true, Storage: true}},
+			expectedBlock: true,
+		},
+		{
+			description:   "version not served",
+			versions:      []apiextensionsv1.CustomResourceDefinitionVersion{{Name: "v1alpha1", Served: false, Storage: true}},
+			expectedBlock: true,
+		},
+		{
+			description: "stored version no longer served",
+			versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true, Storage: true},
+				{Name: "v1alpha0", Served: false},
+			},
+			storedVersions: []string{"v1alpha0", "v1alpha1"},
+			expectedBlock:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			crd := &apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "noderesourcetopologies.topology.node.k8s.io"},
+				Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Versions: tc.versions},
+				Status:     apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: tc.storedVersions},
+			}
+			blocker := NRTAPIBlocker(crd, "v1alpha1")
+			if (blocker != nil) != tc.expectedBlock {
+				t.Errorf("expected blocked=%t got %v", tc.expectedBlock, blocker)
+			}
+		})
+	}
+}
+
+func checkCondition(t *testing.T, conditions []metav1.Condition, condType string, condStatus metav1.ConditionStatus, reason Reason, generation int64) {
+	t.Helper()
+	cond := FindCondition(conditions, condType)
+	if cond == nil {
+		t.Errorf("missing condition %s", condType)
+		return
+	}
+	if cond.Status != condStatus || cond.Reason != string(reason) || cond.ObservedGeneration != generation {
+		t.Errorf("unexpected condition %s: %+v", condType, cond)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+// Package validation checks the ResourceTopologyExporter objects are consumable by the operator and by the exporter.
+// The checks live out of the API package, so its consumers don't pull in the dependencies of the checks.
+package validation
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kubernetes/pkg/kubelet/cm/cpuset"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+const (
+	MinPollInterval = 1 * time.Second
+
+	// MaxNameLength leaves room for the prefixes the operator adds to the names
+	// of the objects it creates, including labels, which are limited to 63 characters.
+	MaxNameLength = 36
+)
+
+var pciIDRegex = regexp.MustCompile(`^[0-9a-fA-F]{4}(:[0-9a-fA-F]{4})?$`)
+
+// ValidateCreate checks a new instance is consumable by the operator and by the exporter.
+func ValidateCreate(rte *topologyexporterv1beta1.ResourceTopologyExporter) error {
+	errs := ValidateName(rte.Name, field.NewPath("metadata").Child("name"))
+	errs = append(errs, ValidateSpec(&rte.Spec, field.NewPath("spec"))...)
+	return invalid(rte, errs)
+}
+
+// ValidateUpdate checks the update of an instance. Only the changed spec fields are validated, so the objects
+// which became invalid, for example created before a check was added, can still have their metadata updated
+// and be deleted.
+func ValidateUpdate(rte, old *topologyexporterv1beta1.ResourceTopologyExporter) error {
+	// removing the finalizers must never be blocked
+	if rte.DeletionTimestamp != nil {
+		return nil
+	}
+	if equality.Semantic.DeepEqual(rte.Spec, old.Spec) {
+		return nil
+	}
+	return invalid(rte, ValidateSpecUpdate(&rte.Spec, &old.Spec, field.NewPath("spec")))
+}
+
+func invalid(rte *topologyexporterv1beta1.ResourceTopologyExporter, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(topologyexporterv1beta1.GroupVersion.WithKind("ResourceTopologyExporter").GroupKind(), rte.Name, errs)
+}
+
+// ValidateName checks the name is usable to derive the names of the objects the operator creates.
+func ValidateName(name string, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(name) > MaxNameLength {
+		errs = append(errs, field.TooLong(fldPath, name, MaxNameLength))
+	}
+	for _, msg := range validation.IsDNS1123Label(name) {
+		errs = append(errs, field.Invalid(fldPath, name, msg))
+	}
+	return errs
+}
+
+// ValidateSpec checks the spec is consumable by the operator and by the exporter.
+func ValidateSpec(spec *topologyexporterv1beta1.ResourceTopologyExporterSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if spec.PollInterval != nil && spec.PollInterval.Duration < MinPollInterval {
+		errs = append(errs, field.Invalid(fldPath.Child("pollInterval"), spec.PollInterval.Duration.String(), "must be at least "+MinPollInterval.String()))
+	}
+	if spec.TopologyManagerPolicy != "" && !isKnownTopologyManagerPolicy(spec.TopologyManagerPolicy) {
+		errs = append(errs, field.NotSupported(fldPath.Child("topologyManagerPolicy"), spec.TopologyManagerPolicy, knownTopologyManagerPolicies()))
+	}
+	if spec.UninstallPolicy != "" && !isKnownUninstallPolicy(spec.UninstallPolicy) {
+		errs = append(errs, field.NotSupported(fldPath.Child("uninstallPolicy"), spec.UninstallPolicy, knownUninstallPolicies()))
+	}
+	if spec.ManagementState != "" && !isKnownManagementState(spec.ManagementState) {
+		errs = append(errs, field.NotSupported(fldPath.Child("managementState"), spec.ManagementState, knownManagementStates()))
+	}
+	for idx, dir := range spec.KubeletStateDirs {
+		if !filepath.IsAbs(dir) {
+			errs = append(errs, field.Invalid(fldPath.Child("kubeletStateDirs").Index(idx), dir, "must be an absolute path"))
+		}
+	}
+	if spec.ReferenceContainer != "" {
+		errs = append(errs, validateReferenceContainer(spec.ReferenceContainer, fldPath.Child("referenceContainer"))...)
+	}
+	if spec.ExporterImage != "" {
+		if _, err := reference.ParseNormalizedNamed(spec.ExporterImage); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("exporterImage"), spec.ExporterImage, err.Error()))
+		}
+	}
+	for idx, secret := range spec.ImagePullSecrets {
+		for _, msg := range validation.IsDNS1123Subdomain(secret.Name) {
+			errs = append(errs, field.Invalid(fldPath.Child("imagePullSecrets").Index(idx).Child("name"), secret.Name, msg))
+		}
+	}
+	return append(errs, ValidateExporterConfig(spec.Config, fldPath.Child("config"))...)
+}
+
+// ValidateSpecUpdate checks the fields changed from the old spec, ignoring the errors the old spec already had.
+func ValidateSpecUpdate(spec, oldSpec *topologyexporterv1beta1.ResourceTopologyExporterSpec, fldPath *field.Path) field.ErrorList {
+	oldErrs := ValidateSpec(oldSpec, fldPath)
+	errs := field.ErrorList{}
+	for _, err := range ValidateSpec(spec, fldPath) {
+		if !hasError(oldErrs, err) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func hasError(errs field.ErrorList, target *field.Error) bool {
+	for _, err := range errs {
+		if err.Type == target.Type && err.Field == target.Field && equality.Semantic.DeepEqual(err.BadValue, target.BadValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateExporterConfig checks the configuration is consumable by the exporter.
+// A nil configuration is valid.
+func ValidateExporterConfig(conf *topologyexporterv1beta1.ExporterConfig, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if conf == nil || conf.Resources == nil {
+		return errs
+	}
+	resPath := fldPath.Child("resources")
+	if _, err := cpuset.Parse(conf.Resources.ReservedCPUs); err != nil {
+		errs = append(errs, field.Invalid(resPath.Child("reservedCPUs"), conf.Resources.ReservedCPUs, err.Error()))
+	}
+	for pciID, resourceName := range conf.Resources.ResourceMapping {
+		if !pciIDRegex.MatchString(pciID) {
+			errs = append(errs, field.Invalid(resPath.Child("resourceMapping").Key(pciID), pciID, `expected "vendor:device" or "vendor"`))
+		}
+		if resourceName == "" {
+			errs = append(errs, field.Required(resPath.Child("resourceMapping").Key(pciID), "empty resource name"))
+		}
+	}
+	return errs
+}
+
+func validateReferenceContainer(spec string, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	items := strings.Split(spec, "/")
+	if len(items) != 3 {
+		return append(errs, field.Invalid(fldPath, spec, `expected "namespace/podname/containername"`))
+	}
+	for _, msg := range validation.IsDNS1123Label(items[0]) {
+		errs = append(errs, field.Invalid(fldPath, spec, "namespace: "+msg))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(items[1]) {
+		errs = append(errs, field.Invalid(fldPath, spec, "pod name: "+msg))
+	}
+	for _, msg := range validation.IsDNS1123Label(items[2]) {
+		errs = append(errs, field.Invalid(fldPath, spec, "container name: "+msg))
+	}
+	return errs
+}
+
+func knownTopologyManagerPolicies() []string {
+	return []string{
+		string(topologyexporterv1beta1.TopologyManagerPolicyNone),
+		string(topologyexporterv1beta1.TopologyManagerPolicyBestEffort),
+		string(topologyexporterv1beta1.TopologyManagerPolicyRestricted),
+		string(topologyexporterv1beta1.TopologyManagerPolicySingleNUMANode),
+	}
+}
+
+func isKnownTopologyManagerPolicy(policy topologyexporterv1beta1.TopologyManagerPolicy) bool {
+	for _, known := range knownTopologyManagerPolicies() {
+		if string(policy) == known {
+			return true
+		}
+	}
+	return false
+}
+
+func knownUninstallPolicies() []string {
+	return []string{
+		string(topologyexporterv1beta1.UninstallPolicyRetain),
+		string(topologyexporterv1beta1.UninstallPolicyDeleteNRTObjects),
+		string(topologyexporterv1beta1.UninstallPolicyDeleteNRTObjectsAndCRD),
+	}
+}
+
+func isKnownUninstallPolicy(policy topologyexporterv1beta1.UninstallPolicy) bool {
+	for _, known := range knownUninstallPolicies() {
+		if string(policy) == known {
+			return true
+		}
+	}
+	return false
+}
+
+func knownManagementStates() []string {
+	return []string{
+		string(topologyexporterv1beta1.ManagementStateManaged),
+		string(topologyexporterv1beta1.ManagementStateUnmanaged),
+		string(topologyexporterv1beta1.ManagementStateRemoved),
+	}
+}
+
+func isKnownManagementState(state topologyexporterv1beta1.ManagementState) bool {
+	for _, known := range knownManagementStates() {
+		if string(state) == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		description string
+		name        string
+		spec        topologyexporterv1beta1.ResourceTopologyExporterSpec
+		expectedErr bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "empty spec",
+			name:        "resourcetopologyexporter",
+		},
+		{
+			description: "full valid spec",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				PollInterval:          &metav1.Duration{Duration: 30 * time.Second},
+				TopologyManagerPolicy: topologyexporterv1beta1.TopologyManagerPolicySingleNUMANode,
+				KubeletStateDirs:      []string{"/host-var/lib/kubelet"},
+				ReferenceContainer:    "rte/rte-pod-xyz/shared-pool-container",
+				UninstallPolicy:       topologyexporterv1beta1.UninstallPolicyDeleteNRTObjects,
+				ManagementState:       topologyexporterv1beta1.ManagementStateUnmanaged,
+				ExporterImage:         "quay.io/k8stopologyawareschedwg/resource-topology-exporter:v0.2.5",
+				ImagePullPolicy:       corev1.PullIfNotPresent,
+				ImagePullSecrets:      []corev1.LocalObjectReference{{Name: "quay-pull"}},
+				Config: &topologyexporterv1beta1.ExporterConfig{
+					Resources: &topologyexporterv1beta1.ResourcesConfig{
+						ReservedCPUs: "0-1",
+						ResourceMapping: map[string]string{
+							"8086:1520": "openshift.io/intelsriov",
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "invalid name",
+			name:        "Pool_A",
+			expectedErr: true,
+		},
+		{
+			description: "name too long",
+			name:        strings.Repeat("a", MaxNameLength+1),
+			expectedErr: true,
+		},
+		{
+			description: "poll interval too short",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				PollInterval: &metav1.Duration{Duration: 100 * time.Millisecond},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "unknown topology manager policy",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				TopologyManagerPolicy: "numa-please",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "unknown uninstall policy",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				UninstallPolicy: "DeleteEverything",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "invalid exporter image",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				ExporterImage: "quay.io/RTE:latest",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "invalid pull secret name",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "Quay_Pull"}},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "unknown management state",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				ManagementState: "Paused",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "relative kubelet state dir",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				KubeletStateDirs: []string{"var/lib/kubelet"},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "reference container missing the container name",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				ReferenceContainer: "rte/rte-pod-xyz",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "reference container with empty namespace",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				ReferenceContainer: "/rte-pod-xyz/shared-pool-container",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "bad reserved cpus",
+			name:        "pool-a",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				Config: &topologyexporterv1beta1.ExporterConfig{
+					Resources: &topologyexporterv1beta1.ResourcesConfig{
+						ReservedCPUs: "0-",
+					},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			rte := &topologyexporterv1beta1.ResourceTopologyExporter{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: tc.name,
+				},
+				Spec: tc.spec,
+			}
+			err := ValidateCreate(rte)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error=%t got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	type testCase struct {
+		description string
+		old         topologyexporterv1beta1.ResourceTopologyExporterSpec
+		spec        topologyexporterv1beta1.ResourceTopologyExporterSpec
+		deleting    bool
+		labels      map[string]string
+		expectedErr bool
+	}
+
+	invalid := topologyexporterv1beta1.ResourceTopologyExporterSpec{
+		PollInterval:    &metav1.Duration{Duration: time.Millisecond},
+		UninstallPolicy: "Wipe",
+	}
+
+	testCases := []testCase{
+		{
+			description: "valid change",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				PollInterval: &metav1.Duration{Duration: time.Minute},
+			},
+		},
+		{
+			description: "invalid change",
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				PollInterval: &metav1.Duration{Duration: time.Millisecond},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "metadata change of an invalid object",
+			old:         invalid,
+			spec:        invalid,
+			labels:      map[string]string{"example.com/team": "numa"},
+		},
+		{
+			description: "valid change of an invalid object",
+			old:         invalid,
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				PollInterval:    &metav1.Duration{Duration: time.Millisecond},
+				UninstallPolicy: topologyexporterv1beta1.UninstallPolicyDeleteNRTObjects,
+			},
+		},
+		{
+			description: "invalid change of an invalid object",
+			old:         invalid,
+			spec: topologyexporterv1beta1.ResourceTopologyExporterSpec{
+				PollInterval:    &metav1.Duration{Duration: 2 * time.Millisecond},
+				UninstallPolicy: "Wipe",
+			},
+			expectedErr: true,
+		},
+		{
+			description: "invalid change while deleting",
+			spec:        invalid,
+			deleting:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			old := &topologyexporterv1beta1.ResourceTopologyExporter{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "this-name-is-longer-than-the-names-allowed-on-create",
+				},
+				Spec: tc.old,
+			}
+			rte := old.DeepCopy()
+			rte.Labels = tc.labels
+			rte.Spec = tc.spec
+			if tc.deleting {
+				now := metav1.Now()
+				rte.DeletionTimestamp = &now
+			}
+			err := ValidateUpdate(rte, old)
+			if (err != nil) != tc.expectedErr {
+				t.Errorf("expected error=%t got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2021 Red Hat, Inc.
+ */
+
+package validation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+)
+
+const webhookPath = "/validate-topologyexporter-openshift-kni-io-v1beta1-resourcetopologyexporter"
+
+//+kubebuilder:webhook:path=/validate-topologyexporter-openshift-kni-io-v1beta1-resourcetopologyexporter,mutating=false,failurePolicy=fail,sideEffects=None,groups=topologyexporter.openshift-kni.io,resources=resourcetopologyexporters,verbs=create;update,versions=v1beta1,name=vresourcetopologyexporter.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// SetupWebhookWithManager registers the validating webhook of the ResourceTopologyExporter type with the manager.
+func SetupWebhookWithManager(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(webhookPath, &webhook.Admission{Handler: &Webhook{}})
+}
+
+// Webhook admits the ResourceTopologyExporter objects passing ValidateCreate and ValidateUpdate.
+type Webhook struct {
+	decoder *admission.Decoder
+}
+
+// InjectDecoder implements admission.DecoderInjector.
+func (wh *Webhook) InjectDecoder(decoder *admission.Decoder) error {
+	wh.decoder = decoder
+	return nil
+}
+
+// Handle implements admission.Handler.
+func (wh *Webhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	rte := &topologyexporterv1beta1.ResourceTopologyExporter{}
+	if err := wh.decoder.DecodeRaw(req.Object, rte); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var err error
+	switch req.Operation {
+	case admissionv1.Create:
+		err = ValidateCreate(rte)
+	case admissionv1.Update:
+		old := &topologyexporterv1beta1.ResourceTopologyExporter{}
+		if err := wh.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = ValidateUpdate(rte, old)
+	}
+	if err == nil {
+		return admission.Allowed("")
+	}
+
+	var apiStatus apierrors.APIStatus
+	if errors.As(err, &apiStatus) {
+		status := apiStatus.Status()
+		return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{Allowed: false, Result: &status}}
+	}
+	return admission.Denied(err.Error())
+}
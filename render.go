@@ -0,0 +1,194 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/go-logr/logr"
+	"github.com/k8stopologyawareschedwg/deployer/pkg/deployer/platform"
+
+	apimanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/api"
+	rtemanifests "github.com/k8stopologyawareschedwg/deployer/pkg/manifests/rte"
+
+	topologyexporterv1alpha1 "github.com/openshift-kni/rte-operator/api/v1alpha1"
+	topologyexporterv1beta1 "github.com/openshift-kni/rte-operator/api/v1beta1"
+	"github.com/openshift-kni/rte-operator/controllers"
+	"github.com/openshift-kni/rte-operator/pkg/images"
+	rtestate "github.com/openshift-kni/rte-operator/pkg/objectstate/rte"
+	"github.com/openshift-kni/rte-operator/pkg/render"
+)
+
+const renderCommand = "render"
+
+// runRender renders offline the objects the operator would apply for a ResourceTopologyExporter,
+// for the clusters where the operator is not allowed to write, e.g. when managed through GitOps.
+func runRender(args []string) error {
+	var instanceFile string
+	var platformName string
+	var namespace string
+	var outputFormat string
+	var outputDir string
+	flags := flag.NewFlagSet(renderCommand, flag.ExitOnError)
+	flags.StringVar(&instanceFile, "instance", "", "file holding the ResourceTopologyExporter to render, - for stdin. Leave empty to render the default instance.")
+	flags.StringVar(&platformName, "platform", "", "platform to render for: kubernetes or openshift")
+	flags.StringVar(&namespace, "namespace", "", "namespace to render the instance in, overriding the one of the instance")
+	flags.StringVar(&outputFormat, "output-format", string(render.FormatYAML), "output format: yaml, json or kustomize")
+	flags.StringVar(&outputDir, "output-dir", "", "directory to write the kustomize output to")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	plat, ok := platform.FromString(platformName)
+	if !ok {
+		return fmt.Errorf("unknown platform %q: rendering offline, the platform can't be detected", platformName)
+	}
+	format, err := render.ParseFormat(outputFormat)
+	if err != nil {
+		return err
+	}
+	if format == render.FormatKustomize && outputDir == "" {
+		return fmt.Errorf("the kustomize output needs an output directory")
+	}
+
+	instance, err := readInstance(instanceFile)
+	if err != nil {
+		return err
+	}
+	if namespace != "" {
+		instance.Namespace = namespace
+	}
+	if instance.Namespace == "" {
+		return fmt.Errorf("no namespace given for the instance")
+	}
+	objs, err := renderInstance(plat, instance)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case render.FormatJSON:
+		return render.JSON(os.Stdout, objs)
+	case render.FormatKustomize:
+		return render.Kustomize(outputDir, objs)
+	}
+	return render.YAML(os.Stdout, objs)
+}
+
+// renderInstance returns the objects the operator would apply on the given platform for the given instance.
+func renderInstance(plat platform.Platform, instance *topologyexporterv1beta1.ResourceTopologyExporter) ([]client.Object, error) {
+	reconciler, err := newOfflineReconciler(plat, instance)
+	if err != nil {
+		return nil, err
+	}
+	return reconciler.RenderObjects(instance)
+}
+
+// renderExporter returns only the exporter objects the operator would apply on the given platform for the given instance,
+// as the deprecated --render-manifests-for flag always did.
+func renderExporter(plat platform.Platform, instance *topologyexporterv1beta1.ResourceTopologyExporter) ([]client.Object, error) {
+	reconciler, err := newOfflineReconciler(plat, instance)
+	if err != nil {
+		return nil, err
+	}
+	mf, err := reconciler.RenderManifests(instance)
+	if err != nil {
+		return nil, err
+	}
+	return mf.ToObjects(), nil
+}
+
+// newOfflineReconciler defaults the given instance, and returns a reconciler able to render it without a cluster.
+func newOfflineReconciler(plat platform.Platform, instance *topologyexporterv1beta1.ResourceTopologyExporter) (*controllers.ResourceTopologyExporterReconciler, error) {
+	if instance.Name == "" {
+		instance.Name = rtestate.DefaultInstanceName
+	}
+	// what the admission webhook would do
+	instance.Default()
+	if instance.Spec.ManagementState != topologyexporterv1beta1.ManagementStateManaged {
+		return nil, fmt.Errorf("nothing to render: the operator applies nothing to a %s instance", instance.Spec.ManagementState)
+	}
+
+	apiManifests, err := apimanifests.GetManifests(plat)
+	if err != nil {
+		return nil, err
+	}
+	rteManifests, err := rtemanifests.GetManifests(plat)
+	if err != nil {
+		return nil, err
+	}
+	return &controllers.ResourceTopologyExporterReconciler{
+		Log:          logr.Discard(),
+		Scheme:       scheme,
+		APIManifests: apiManifests,
+		RTEManifests: rteManifests,
+		Platform:     plat,
+		DefaultImage: images.GetOfflineDefaultSelection(),
+	}, nil
+}
+
+// readInstance reads the instance from the given file, in any API version. No file means the default instance.
+func readInstance(path string) (*topologyexporterv1beta1.ResourceTopologyExporter, error) {
+	instance := &topologyexporterv1beta1.ResourceTopologyExporter{}
+	if path == "" {
+		return instance, nil
+	}
+
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	typeMeta := metav1.TypeMeta{}
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+	if typeMeta.Kind != "ResourceTopologyExporter" {
+		return nil, fmt.Errorf("%s: expected a ResourceTopologyExporter, found kind %q", path, typeMeta.Kind)
+	}
+	switch typeMeta.APIVersion {
+	case topologyexporterv1beta1.GroupVersion.String():
+		if err := yaml.UnmarshalStrict(data, instance); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case topologyexporterv1alpha1.GroupVersion.String():
+		old := &topologyexporterv1alpha1.ResourceTopologyExporter{}
+		if err := yaml.UnmarshalStrict(data, old); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if err := old.ConvertTo(instance); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported apiVersion %q", path, typeMeta.APIVersion)
+	}
+	return instance, nil
+}